@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Create, check and show the config file",
+}
+
+func init() {
+	RootCmd.AddCommand(configCmd)
+
+	configCmd.PersistentFlags().StringVar(&cfgPath, "config", "", "config file (default ~/.godeez/config.toml)")
+	configCmd.PersistentFlags().StringVar(&profileName, "profile", "", "config profile to use (default default_profile from the config)")
+}
+
+// errNoInput is returned by prompts when the input ends before an answer.
+var errNoInput = errors.New("no input")
+
+// prompter asks questions on the output of a command and reads the answers
+// from its input.
+type prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	// terminal is the file descriptor of the input when it is a terminal,
+	// so that secrets are read without being echoed, and -1 otherwise
+	terminal int
+}
+
+func newPrompter(cmd *cobra.Command) *prompter {
+	p := &prompter{
+		in:       bufio.NewReader(cmd.InOrStdin()),
+		out:      cmd.OutOrStdout(),
+		terminal: -1,
+	}
+	if f, ok := cmd.InOrStdin().(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		p.terminal = int(f.Fd())
+	}
+
+	return p
+}
+
+// ask returns the trimmed answer to a question, or fallback when the answer
+// is empty.
+func (p *prompter) ask(question, fallback string) (string, error) {
+	if fallback != "" {
+		fmt.Fprintf(p.out, "%s [%s]: ", question, fallback)
+	} else {
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+
+	line, err := p.in.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		fmt.Fprintln(p.out)
+		return "", errNoInput
+	}
+
+	answer := strings.TrimSpace(line)
+	if answer == "" {
+		return fallback, nil
+	}
+
+	return answer, nil
+}
+
+// askSecret is like ask, without echoing the answer on a terminal.
+func (p *prompter) askSecret(question string) (string, error) {
+	if p.terminal < 0 {
+		return p.ask(question, "")
+	}
+
+	fmt.Fprintf(p.out, "%s: ", question)
+	secret, err := term.ReadPassword(p.terminal)
+	fmt.Fprintln(p.out)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(secret)), nil
+}
+
+// confirm asks a yes/no question, defaulting to no.
+func (p *prompter) confirm(question string) (bool, error) {
+	answer, err := p.ask(question+" [y/N]", "")
+	if err != nil {
+		return false, err
+	}
+
+	answer = strings.ToLower(answer)
+
+	return answer == "y" || answer == "yes", nil
+}
+
+// printAccount describes the account a session is logged in to.
+func printAccount(w io.Writer, session *deezer.Session) {
+	name := session.UserName
+	if name == "" {
+		name = "unknown"
+	}
+
+	fmt.Fprintf(w, "Logged in as %s (user %s)\n", name, session.UserID)
+	fmt.Fprintf(w, "    Premium:        %s\n", yesNo(session.Premium))
+	fmt.Fprintf(w, "    Web offline:    %s\n", yesNo(session.WebOffline))
+	fmt.Fprintf(w, "    Mobile offline: %s\n", yesNo(session.MobileOffline))
+	if !session.Premium {
+		fmt.Fprintln(w, "    Only mp3_128 can be downloaded with this account")
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
+
+// mask hides a secret, keeping its last 4 characters when it is long enough
+// for them not to give it away.
+func mask(secret string) string {
+	switch {
+	case secret == "":
+		return ""
+	case len(secret) < 16:
+		return strings.Repeat("*", 8)
+	default:
+		return strings.Repeat("*", 8) + secret[len(secret)-4:]
+	}
+}
+
+// tomlString quotes a string for a TOML file, as a literal string when
+// possible as in the documented examples.
+func tomlString(s string) string {
+	if !strings.ContainsAny(s, "'\n\r") {
+		return "'" + s + "'"
+	}
+
+	// JSON escapes are valid in TOML basic strings
+	quoted, _ := json.Marshal(s)
+
+	return string(quoted)
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/fileutil"
+	"github.com/mathismqn/godeez/internal/template"
+	"github.com/spf13/cobra"
+)
+
+var initForce bool
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create the config file interactively",
+	Long: `Create the config file interactively.
+
+The ARL cookie is checked by logging in to Deezer, and the status of the
+account is printed. Secrets are not echoed when typed in a terminal.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, err := config.Path(cfgPath)
+		if err != nil {
+			return err
+		}
+
+		p := newPrompter(cmd)
+		out := cmd.OutOrStdout()
+
+		if fileutil.FileExists(path) && !initForce {
+			overwrite, err := p.confirm(fmt.Sprintf("%s already exists, overwrite it?", path))
+			if err != nil {
+				return err
+			}
+			if !overwrite {
+				fmt.Fprintln(out, "Config file left unchanged")
+				return nil
+			}
+		}
+
+		fmt.Fprintf(out, "Creating config file at %s\n\n", path)
+
+		var cfg config.Config
+		for {
+			cfg.ArlCookie, err = p.askSecret("ARL cookie")
+			if err != nil {
+				return err
+			}
+			if cfg.ArlCookie == "" {
+				fmt.Fprintln(out, "The ARL cookie is required")
+				continue
+			}
+
+			session, err := deezer.Authenticate(cmd.Context(), cfg.ArlCookie)
+			if err == nil {
+				printAccount(out, session)
+				break
+			}
+
+			fmt.Fprintf(out, "Failed to log in: %v\n", err)
+			keep, err := p.confirm("Keep this ARL cookie anyway?")
+			if err != nil {
+				return err
+			}
+			if keep {
+				break
+			}
+		}
+
+		for {
+			cfg.SecretKey, err = p.askSecret("Secret key (16 characters)")
+			if err != nil {
+				return err
+			}
+			if len(cfg.SecretKey) == 16 {
+				break
+			}
+			fmt.Fprintf(out, "The secret key must be 16 bytes long, got %d\n", len(cfg.SecretKey))
+		}
+
+		cfg.OutputDir, err = p.ask("Output directory (leave empty for ~/Music/GoDeez)", "")
+		if err != nil {
+			return err
+		}
+
+		for {
+			cfg.Template, err = p.ask("Filename template (leave empty for the default)", "")
+			if err != nil {
+				return err
+			}
+			if cfg.Template == "" {
+				break
+			}
+			_, err := template.Parse(cfg.Template)
+			if err == nil {
+				break
+			}
+			fmt.Fprintf(out, "Invalid template: %v\n", err)
+		}
+
+		if err := fileutil.EnsureDir(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("failed to create config directory: %w", err)
+		}
+		// The file holds the ARL cookie, which gives access to the account
+		if err := os.WriteFile(path, []byte(configFileContent(&cfg)), 0600); err != nil {
+			return fmt.Errorf("failed to write config file: %w", err)
+		}
+		fmt.Fprintf(out, "\nConfig file written to %s\n", path)
+
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configInitCmd)
+
+	configInitCmd.Flags().BoolVarP(&initForce, "force", "f", false, "overwrite the config file without asking")
+}
+
+// configFileContent returns the content of a config file holding the
+// top-level values of cfg.
+func configFileContent(cfg *config.Config) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "arl_cookie = %s\n", tomlString(cfg.ArlCookie))
+	fmt.Fprintf(&b, "secret_key = %s\n", tomlString(cfg.SecretKey))
+	fmt.Fprintf(&b, "output_dir = %s\n", tomlString(cfg.OutputDir))
+	if cfg.Template != "" {
+		fmt.Fprintf(&b, "template = %s\n", tomlString(cfg.Template))
+	}
+
+	return b.String()
+}
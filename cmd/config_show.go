@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/downloader"
+	"github.com/spf13/cobra"
+)
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective config, with secrets masked",
+	Long: `Print the effective config of the selected profile, with secrets masked.
+
+The values are those used by the other commands when no flag overrides them:
+the built-in defaults, overridden by the top level of the config file, then
+by the selected profile, then by environment variables.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+
+		path, err := config.Path(cfgPath)
+		if err != nil {
+			return err
+		}
+		file, err := config.Load(cfgPath)
+		if err != nil {
+			return err
+		}
+		cfg, err := file.SelectProfile(profileName)
+		if err != nil {
+			return err
+		}
+		invalid := cfg.Validate()
+		if invalid == nil {
+			download := downloader.ConfigOptions(cfg.Download)
+			if err := download.Validate(); err != nil {
+				invalid = fmt.Errorf("invalid download option: %w", err)
+			}
+		}
+
+		fmt.Fprintf(out, "# Config file: %s\n", path)
+		if cfg.Profile != "" {
+			fmt.Fprintf(out, "# Profile:     %s\n", cfg.Profile)
+		}
+		fmt.Fprintf(out, "arl_cookie = %s\n", tomlString(mask(cfg.ArlCookie)))
+		fmt.Fprintf(out, "secret_key = %s\n", tomlString(mask(cfg.SecretKey)))
+		fmt.Fprintf(out, "output_dir = %s\n", tomlString(cfg.OutputDir))
+		fmt.Fprintf(out, "template = %s\n", tomlString(cfg.Template))
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "[endpoints]")
+		fmt.Fprintf(out, "deezer = %s\n", tomlString(cfg.Endpoints.Deezer))
+		fmt.Fprintf(out, "media = %s\n", tomlString(cfg.Endpoints.Media))
+		fmt.Fprintf(out, "images = %s\n", tomlString(cfg.Endpoints.Images))
+		fmt.Fprintf(out, "songbpm = %s\n", tomlString(cfg.Endpoints.SongBPM))
+		fmt.Fprintf(out, "lastfm = %s\n", tomlString(cfg.Endpoints.LastFM))
+
+		download := downloader.ConfigOptions(cfg.Download)
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, "[download]")
+		fmt.Fprintf(out, "quality = %s\n", tomlString(download.Quality))
+		fmt.Fprintf(out, "timeout = %s\n", tomlString(download.Timeout.String()))
+		fmt.Fprintf(out, "concurrency = %d\n", download.Concurrency)
+		fmt.Fprintf(out, "retries = %d\n", download.Retries)
+		fmt.Fprintf(out, "retry_backoff = %s\n", tomlString(download.RetryBackoff.String()))
+		fmt.Fprintf(out, "bpm = %t\n", download.BPM)
+		fmt.Fprintf(out, "genre = %t\n", download.Genre)
+		fmt.Fprintf(out, "strict = %t\n", download.Strict)
+		fmt.Fprintf(out, "lyrics = %t\n", download.Lyrics)
+		fmt.Fprintf(out, "replaygain = %t\n", download.ReplayGain)
+		fmt.Fprintf(out, "limit = %d\n", download.Limit)
+		fmt.Fprintf(out, "output = %s\n", tomlString(download.Output))
+
+		if invalid != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "\nWarning: %v, run \"godeez config validate\" for details\n", invalid)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/downloader"
+	"github.com/spf13/cobra"
+)
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file and the Deezer account",
+	Long: `Check the config file and the Deezer account.
+
+Every problem of the selected profile is reported, then the ARL cookie is
+checked by logging in to Deezer and the status of the account is printed.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		out := cmd.OutOrStdout()
+
+		path, err := config.Path(cfgPath)
+		if err != nil {
+			return err
+		}
+		file, err := config.Load(cfgPath)
+		if err != nil {
+			return err
+		}
+		cfg, err := file.SelectProfile(profileName)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "Config file: %s\n", path)
+		if cfg.Profile != "" {
+			fmt.Fprintf(out, "Profile:     %s\n", cfg.Profile)
+		}
+		fmt.Fprintln(out)
+
+		problems := 0
+		for _, err := range cfg.Check() {
+			fmt.Fprintf(out, "✖ %v\n", err)
+			problems++
+		}
+		if problems == 0 {
+			fmt.Fprintln(out, "✔ arl_cookie, secret_key and template are set and valid")
+		}
+
+		download := downloader.ConfigOptions(cfg.Download)
+		if err := download.Validate(); err != nil {
+			fmt.Fprintf(out, "✖ invalid download option: %v\n", err)
+			problems++
+		} else {
+			fmt.Fprintln(out, "✔ download options are valid")
+		}
+		// Fill in the defaults, the problems were reported above
+		cfg.Validate()
+
+		if cfg.ArlCookie != "" {
+			session, err := deezer.AuthenticateWithOptions(cmd.Context(), cfg.ArlCookie, deezer.SessionOptions{
+				Retry:     deezer.DefaultRetryPolicy,
+				Endpoints: cfg.Endpoints,
+			})
+			if err != nil {
+				fmt.Fprintf(out, "✖ failed to log in: %v\n", err)
+				problems++
+			} else {
+				fmt.Fprint(out, "✔ ")
+				printAccount(out, session)
+			}
+		}
+
+		if problems > 0 {
+			return fmt.Errorf("config has %d problem(s)", problems)
+		}
+		fmt.Fprintln(out, "\nConfig is valid")
+
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
@@ -5,16 +5,17 @@ import (
 	"errors"
 	"fmt"
 	"strings"
-	"time"
 
 	"github.com/mathismqn/godeez/internal/config"
 	"github.com/mathismqn/godeez/internal/downloader"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
-	opts    downloader.Options
-	cfgPath string
+	opts        downloader.Options
+	cfgPath     string
+	profileName string
 )
 
 var downloadCmd = &cobra.Command{
@@ -25,12 +26,7 @@ var downloadCmd = &cobra.Command{
 func init() {
 	RootCmd.AddCommand(downloadCmd)
 
-	downloadCmd.PersistentFlags().StringVar(&cfgPath, "config", "", "config file (default ~/.godeez/config.toml)")
-	downloadCmd.PersistentFlags().StringVarP(&opts.Quality, "quality", "q", "mp3_320", "download quality [mp3_128, mp3_320, flac]")
-	downloadCmd.PersistentFlags().DurationVarP(&opts.Timeout, "timeout", "t", 2*time.Minute, "timeout for each download (e.g. 10s, 1m, 2m30s)")
-	downloadCmd.PersistentFlags().BoolVar(&opts.BPM, "bpm", false, "fetch BPM/key and add to file tags")
-	downloadCmd.PersistentFlags().BoolVar(&opts.Genre, "genre", false, "fetch genre and add to file tags")
-	downloadCmd.PersistentFlags().BoolVar(&opts.Strict, "strict", false, "fail the song download if the quality is not available")
+	addDownloadFlags(downloadCmd.PersistentFlags())
 
 	downloadCmd.AddCommand(
 		newDownloadCmd("album"),
@@ -40,6 +36,47 @@ func init() {
 	)
 }
 
+// addDownloadFlags registers the flags shared by every command that
+// downloads songs.
+func addDownloadFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&cfgPath, "config", "", "config file (default ~/.godeez/config.toml)")
+	flags.StringVar(&profileName, "profile", "", "config profile to use (default default_profile from the config)")
+	flags.StringVarP(&opts.Quality, "quality", "q", downloader.DefaultQuality, "download quality [mp3_128, mp3_320, flac]")
+	flags.DurationVarP(&opts.Timeout, "timeout", "t", downloader.DefaultTimeout, "timeout for each download (e.g. 10s, 1m, 2m30s)")
+	flags.IntVarP(&opts.Concurrency, "concurrency", "c", downloader.DefaultConcurrency, "number of songs to download in parallel")
+	flags.IntVar(&opts.Retries, "retries", downloader.DefaultRetries, "number of times a failed request is retried")
+	flags.DurationVar(&opts.RetryBackoff, "retry-backoff", downloader.DefaultRetryBackoff, "delay before the first retry, doubled on each retry")
+	flags.BoolVar(&opts.BPM, "bpm", false, "fetch BPM/key and add to file tags")
+	flags.BoolVar(&opts.Genre, "genre", false, "fetch genre and add to file tags")
+	flags.BoolVar(&opts.Strict, "strict", false, "fail the song download if the quality is not available")
+	flags.BoolVar(&opts.Lyrics, "lyrics", false, "fetch lyrics, add them to file tags and write synced lyrics to .lrc files")
+	flags.BoolVar(&opts.ReplayGain, "replaygain", false, "compute the loudness of each song and add ReplayGain tags")
+	flags.StringVar(&opts.Template, "template", "", "path template for downloaded files, relative to the output directory")
+	flags.StringVar(&opts.Output, "output", downloader.OutputText, "output format [text, json]")
+}
+
+// prepareDownload loads the config into the command context and validates
+// the download options.
+func prepareDownload(cmd *cobra.Command, args []string) error {
+	appConfig, err := config.New(cfgPath, profileName)
+	if err != nil {
+		return err
+	}
+	cmd.SetContext(context.WithValue(cmd.Context(), "appConfig", appConfig))
+
+	applyDownloadConfig(&opts, cmd.Flags(), appConfig.Download)
+	opts.Quality = strings.ToLower(opts.Quality)
+	opts.Output = strings.ToLower(opts.Output)
+
+	releaseTypes := make([]string, len(opts.ReleaseTypes))
+	for i, releaseType := range opts.ReleaseTypes {
+		releaseTypes[i] = strings.ToLower(strings.TrimSpace(releaseType))
+	}
+	opts.ReleaseTypes = releaseTypes
+
+	return opts.Validate()
+}
+
 func newDownloadCmd(resourceType string) *cobra.Command {
 	article := "a"
 	if resourceType == "album" {
@@ -47,45 +84,53 @@ func newDownloadCmd(resourceType string) *cobra.Command {
 	}
 
 	cmd := &cobra.Command{
-		Use:   fmt.Sprintf("%s <%s_id>", resourceType, resourceType),
-		Short: fmt.Sprintf("Download songs from %s %s", article, resourceType),
-		Args:  cobra.ExactArgs(1),
-		PreRunE: func(cmd *cobra.Command, args []string) error {
-			appConfig, err := config.New(cfgPath)
-			if err != nil {
-				return err
-			}
-			cmd.SetContext(context.WithValue(cmd.Context(), "appConfig", appConfig))
-
-			opts.Quality = strings.ToLower(opts.Quality)
-
-			return opts.Validate()
-		},
+		Use:     fmt.Sprintf("%s <%s_id>", resourceType, resourceType),
+		Short:   fmt.Sprintf("Download songs from %s %s", article, resourceType),
+		Args:    cobra.ExactArgs(1),
+		PreRunE: prepareDownload,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := cmd.Context()
 			appConfigVal := ctx.Value("appConfig")
 			appConfig, _ := appConfigVal.(*config.Config)
 
-			dl := downloader.New(appConfig, resourceType)
-			if err := dl.Run(ctx, opts, args[0]); err != nil {
-				if errors.Is(err, context.Canceled) {
-					return nil
-				}
-
-				return err
+			var err error
+			if resourceType == "artist" && opts.Discography {
+				err = downloader.NewBatch(appConfig).RunDiscography(ctx, opts, args[0])
+			} else {
+				err = downloader.New(appConfig, resourceType).Run(ctx, opts, args[0])
 			}
 
-			return nil
+			return ignoreCanceled(err)
 		},
 	}
 
 	switch resourceType {
 	case "artist":
 		cmd.Short = "Download top songs from an artist"
-		cmd.Flags().IntVarP(&opts.Limit, "limit", "l", 10, "number of songs to download")
+		cmd.Flags().IntVarP(&opts.Limit, "limit", "l", downloader.DefaultLimit, "number of songs to download")
+		cmd.Flags().BoolVar(&opts.Discography, "discography", false, "download every release of the artist instead of its top songs")
+		cmd.Flags().StringSliceVar(&opts.ReleaseTypes, "release-types", downloader.DefaultReleaseTypes, "release types to download with --discography [album, ep, single, compilation, bundle]")
+		cmd.Flags().StringVar(&opts.Since, "since", "", "only download releases from this date with --discography (YYYY-MM-DD)")
+		cmd.Flags().StringVar(&opts.Until, "until", "", "only download releases up to this date with --discography (YYYY-MM-DD)")
 	case "track":
 		cmd.Short = "Download a single track"
 	}
 
 	return cmd
 }
+
+// applyDownloadConfig sets the options whose flag was not given to their
+// value in the config, flags taking precedence over the config.
+func applyDownloadConfig(o *downloader.Options, flags *pflag.FlagSet, d config.Download) {
+	o.ApplyConfig(d, flags.Changed)
+}
+
+// ignoreCanceled returns nil when err is due to the user interrupting the
+// command.
+func ignoreCanceled(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+
+	return err
+}
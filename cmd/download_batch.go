@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/downloader"
+	"github.com/spf13/cobra"
+)
+
+var downloadBatchCmd = &cobra.Command{
+	Use:   "batch <file|->",
+	Short: "Download every link listed in a file, or in stdin with -",
+	Long: `Download every link listed in a file, or in stdin with -.
+
+Each line holds a Deezer URL (https://www.deezer.com/en/album/123), a short
+link (https://deezer.page.link/...), a deezer:// URI or a typed ID
+(album:123, playlist:123, artist:123, track:123). Empty lines and lines
+starting with # are ignored.`,
+	Args:    cobra.ExactArgs(1),
+	PreRunE: prepareDownload,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		appConfig, _ := ctx.Value("appConfig").(*config.Config)
+
+		links, err := readLinks(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		name := args[0]
+		if name == "-" {
+			name = "stdin"
+		}
+
+		return ignoreCanceled(downloader.NewBatch(appConfig).Run(ctx, opts, name, links))
+	},
+}
+
+func init() {
+	downloadCmd.AddCommand(downloadBatchCmd)
+
+	downloadBatchCmd.Flags().IntVarP(&opts.Limit, "limit", "l", downloader.DefaultLimit, "number of songs to download for artists")
+}
+
+// readLinks reads and resolves the links of path, or of stdin when path is
+// "-". Duplicate links are dropped.
+func readLinks(ctx context.Context, path string) ([]deezer.Link, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+
+	httpClient := &http.Client{Timeout: 10 * time.Second}
+	seen := make(map[deezer.Link]bool)
+	var links []deezer.Link
+
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		link, err := deezer.ResolveLink(ctx, httpClient, line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		if seen[link] {
+			continue
+		}
+		seen[link] = true
+		links = append(links, link)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/downloader"
+	"github.com/spf13/cobra"
+)
+
+var downloadFavoritesCmd = &cobra.Command{
+	Use:     "favorites",
+	Short:   "Download your favourite tracks",
+	Args:    cobra.NoArgs,
+	PreRunE: prepareDownload,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig, _ := cmd.Context().Value("appConfig").(*config.Config)
+
+		return ignoreCanceled(downloader.New(appConfig, "favorites").Run(cmd.Context(), opts, ""))
+	},
+}
+
+var downloadLibraryCmd = &cobra.Command{
+	Use:   "library",
+	Short: "Download your favourite tracks and the albums and playlists of your library",
+	Long: `Download your favourite tracks and the albums and playlists of your library.
+
+Each album and playlist is downloaded to its own folder, and songs found in
+several of them are only downloaded once.`,
+	Args:    cobra.NoArgs,
+	PreRunE: prepareDownload,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runLibrary(cmd, true, &deezer.LibraryAlbums{}, &deezer.LibraryPlaylists{})
+	},
+}
+
+var downloadLibraryAlbumsCmd = &cobra.Command{
+	Use:     "albums",
+	Short:   "Download the albums of your library",
+	Args:    cobra.NoArgs,
+	PreRunE: prepareDownload,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runLibrary(cmd, false, &deezer.LibraryAlbums{})
+	},
+}
+
+var downloadLibraryPlaylistsCmd = &cobra.Command{
+	Use:     "playlists",
+	Short:   "Download the playlists you created or follow",
+	Args:    cobra.NoArgs,
+	PreRunE: prepareDownload,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return runLibrary(cmd, false, &deezer.LibraryPlaylists{})
+	},
+}
+
+func init() {
+	downloadCmd.AddCommand(downloadFavoritesCmd, downloadLibraryCmd)
+	downloadLibraryCmd.AddCommand(downloadLibraryAlbumsCmd, downloadLibraryPlaylistsCmd)
+}
+
+func runLibrary(cmd *cobra.Command, favorites bool, collections ...deezer.Collection) error {
+	appConfig, _ := cmd.Context().Value("appConfig").(*config.Config)
+
+	return ignoreCanceled(downloader.NewBatch(appConfig).RunLibrary(cmd.Context(), opts, favorites, collections...))
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/downloader"
+	"github.com/spf13/cobra"
+)
+
+var getCmd = &cobra.Command{
+	Use:   "get <url>",
+	Short: "Download the album, playlist, artist or track of a Deezer link",
+	Long: `Download the album, playlist, artist or track of a Deezer link.
+
+The link can be a Deezer URL (https://www.deezer.com/en/album/123), a short
+link (https://deezer.page.link/...), a deezer:// URI or a typed ID
+(album:123).`,
+	Args:    cobra.ExactArgs(1),
+	PreRunE: prepareDownload,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx := cmd.Context()
+		appConfig, _ := ctx.Value("appConfig").(*config.Config)
+
+		httpClient := &http.Client{Timeout: 10 * time.Second}
+		link, err := deezer.ResolveLink(ctx, httpClient, args[0])
+		if err != nil {
+			return err
+		}
+
+		return ignoreCanceled(downloader.New(appConfig, link.Type).Run(ctx, opts, link.ID))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(getCmd)
+
+	addDownloadFlags(getCmd.Flags())
+	getCmd.Flags().IntVarP(&opts.Limit, "limit", "l", downloader.DefaultLimit, "number of songs to download for artists")
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/downloader"
+	"github.com/spf13/cobra"
+)
+
+var retagOpts downloader.RetagOptions
+
+var retagCmd = &cobra.Command{
+	Use:   "retag",
+	Short: "Rewrite the tags of downloaded songs",
+	Long: `Rewrite the tags of the songs downloaded with the profile, as recorded in
+the database, with their metadata fetched again from Deezer. Songs whose file
+no longer exists are skipped.
+
+The basic, genre, bpm and cover tags are rewritten by default. Use --only to
+choose the groups of tags to rewrite, among ` + strings.Join(downloader.RetagGroups, ", ") + `.
+--lyrics and --replaygain add their group to the others.
+
+With --replaygain, the loudness of every song is analysed and its ReplayGain
+tags are written. Songs tagged with the same album and album artist are
+analysed together for the album gain and peak.
+
+With --dry-run, the old and new value of every changed tag is printed and no
+file is written.`,
+	Args: cobra.NoArgs,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := retagOpts.Validate(); err != nil {
+			return err
+		}
+
+		return openStore(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig, _ := cmd.Context().Value("appConfig").(*config.Config)
+
+		return ignoreCanceled(downloader.Retag(cmd.Context(), appConfig, retagOpts))
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(retagCmd)
+
+	retagCmd.Flags().StringVar(&cfgPath, "config", "", "config file (default ~/.godeez/config.toml)")
+	retagCmd.Flags().StringVar(&profileName, "profile", "", "config profile to use (default default_profile from the config)")
+	retagCmd.Flags().StringSliceVar(&retagOpts.Only, "only", nil, "groups of tags to rewrite, comma separated (default basic,genre,bpm,cover)")
+	retagCmd.Flags().BoolVar(&retagOpts.Lyrics, "lyrics", false, "also rewrite the lyrics and write synced lyrics to .lrc files")
+	retagCmd.Flags().BoolVar(&retagOpts.ReplayGain, "replaygain", false, "also analyse the loudness of the songs and write their ReplayGain tags")
+	retagCmd.Flags().BoolVar(&retagOpts.DryRun, "dry-run", false, "print the changed tags without writing them")
+}
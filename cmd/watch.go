@@ -1,18 +1,110 @@
 package cmd
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/store"
+	"github.com/mathismqn/godeez/internal/watcher"
 	"github.com/spf13/cobra"
 )
 
 var watchCmd = &cobra.Command{
 	Use:   "watch",
 	Short: "Watch playlists and auto-download new tracks",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("failed to get home directory: %w", err)
+		}
+
+		if err := watcher.EnsureAutostart(homeDir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to install autostart for watcher: %v\n", err)
+		}
+
+		return nil
+	},
 }
 
-// TEMPORARILY DISABLED:
-// The `watch` command and all its subcommands are currently disabled
-// due to known issues (e.g., database access conflicts with `download`).
-// To re-enable, uncomment the line below.
 func init() {
-	// RootCmd.AddCommand(watchCmd)
+	RootCmd.AddCommand(watchCmd)
+
+	watchCmd.PersistentFlags().StringVar(&cfgPath, "config", "", "config file (default ~/.godeez/config.toml)")
+	watchCmd.PersistentFlags().StringVar(&profileName, "profile", "", "config profile to use (default default_profile from the config)")
+}
+
+// openStore loads the config, which connects to the watcher's database or
+// opens it directly.
+func openStore(cmd *cobra.Command, args []string) error {
+	appConfig, err := config.New(cfgPath, profileName)
+	if err != nil {
+		return err
+	}
+	cmd.SetContext(context.WithValue(cmd.Context(), "appConfig", appConfig))
+
+	return nil
+}
+
+func validateMirrorPolicy(policy string) error {
+	switch policy {
+	case store.MirrorKeep, store.MirrorArchive, store.MirrorDelete:
+		return nil
+	default:
+		return fmt.Errorf("invalid mirror policy: %s", policy)
+	}
+}
+
+// printMirrorPlan prints what policy would do right now with the files of
+// the songs removed from a watched playlist.
+func printMirrorPlan(cmd *cobra.Command, playlist *store.WatchedPlaylist, policy, outputDir string) error {
+	snapshot, err := store.GetPlaylistSnapshot(playlist.Profile, playlist.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get snapshot of playlist %s: %w", playlist.ID, err)
+	}
+
+	preview := *playlist
+	preview.Mirror = policy
+	actions, err := watcher.PlanMirror(&preview, snapshot, outputDir)
+	if err != nil {
+		return fmt.Errorf("failed to plan mirror: %w", err)
+	}
+
+	out := cmd.OutOrStdout()
+	if len(actions) == 0 {
+		fmt.Fprintf(out, "No files to %s for playlist %s\n", policy, playlist.ID)
+		return nil
+	}
+	fmt.Fprintf(out, "Mirror plan for playlist %s (%s):\n", playlist.ID, policy)
+	for _, action := range actions {
+		fmt.Fprintf(out, "    %s\n", action)
+	}
+
+	return nil
+}
+
+// confirmMirror asks before setting a policy that archives or deletes
+// files, unless yes is set.
+func confirmMirror(cmd *cobra.Command, playlistID, policy string, yes bool) (bool, error) {
+	if policy == store.MirrorKeep || yes {
+		return true, nil
+	}
+
+	p := newPrompter(cmd)
+	ok, err := p.confirm(fmt.Sprintf("%s the files of songs removed from playlist %s?", mirrorVerb(policy), playlistID))
+	if errors.Is(err, errNoInput) {
+		return false, fmt.Errorf("confirmation required, use --yes to set the %s policy without asking", policy)
+	}
+
+	return ok, err
+}
+
+func mirrorVerb(policy string) string {
+	if policy == store.MirrorArchive {
+		return "Archive"
+	}
+
+	return "Delete"
 }
@@ -2,20 +2,54 @@ package cmd
 
 import (
 	"fmt"
+	"path/filepath"
 	"strings"
-	"time"
 
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/downloader"
 	"github.com/mathismqn/godeez/internal/store"
 	"github.com/spf13/cobra"
 )
 
+var (
+	// watchOpts holds the options of watch add, which has no flag for the
+	// options it does not store, so that they keep their built-in defaults
+	watchOpts    = downloader.DefaultOptions()
+	mirrorPolicy string
+)
+
 var watchAddCmd = &cobra.Command{
 	Use:   "add <playlist_id>",
 	Short: "Add a playlist to the watch list",
 	Args:  cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := openStore(cmd, args); err != nil {
+			return err
+		}
+		appConfig, _ := cmd.Context().Value("appConfig").(*config.Config)
+		applyDownloadConfig(&watchOpts, cmd.Flags(), appConfig.Download)
+
+		watchOpts.Quality = strings.ToLower(watchOpts.Quality)
+		mirrorPolicy = strings.ToLower(mirrorPolicy)
+		if err := validateMirrorPolicy(mirrorPolicy); err != nil {
+			return err
+		}
+		if watchOpts.OutputDir != "" {
+			// The watcher runs from another working directory
+			outputDir, err := filepath.Abs(watchOpts.OutputDir)
+			if err != nil {
+				return fmt.Errorf("invalid output directory: %w", err)
+			}
+			watchOpts.OutputDir = outputDir
+		}
+
+		return watchOpts.Validate()
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig, _ := cmd.Context().Value("appConfig").(*config.Config)
+
 		id := args[0]
-		ok, err := store.IsWatched(id)
+		ok, err := store.IsWatched(appConfig.Profile, id)
 		if err != nil {
 			return err
 		}
@@ -25,15 +59,46 @@ var watchAddCmd = &cobra.Command{
 		}
 
 		playlist := &store.WatchedPlaylist{
-			ID:      id,
-			Quality: strings.ToLower(opts.Quality),
-			BPM:     opts.BPM,
-			Timeout: opts.Timeout,
+			ID:         id,
+			Quality:    watchOpts.Quality,
+			BPM:        watchOpts.BPM,
+			Genre:      watchOpts.Genre,
+			Strict:     watchOpts.Strict,
+			Lyrics:     watchOpts.Lyrics,
+			ReplayGain: watchOpts.ReplayGain,
+			Timeout:    watchOpts.Timeout,
+			OutputDir:  watchOpts.OutputDir,
+			Template:   watchOpts.Template,
+			Mirror:     mirrorPolicy,
+			Profile:    appConfig.Profile,
+		}
+
+		if mirrorPolicy != store.MirrorKeep {
+			outputDir := watchOpts.OutputDir
+			if outputDir == "" {
+				outputDir = appConfig.OutputDir
+			}
+			if err := printMirrorPlan(cmd, playlist, mirrorPolicy, outputDir); err != nil {
+				return err
+			}
+			ok, err := confirmMirror(cmd, id, mirrorPolicy, mirrorYes)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				fmt.Printf("Playlist %s not added to watch list\n", id)
+				return nil
+			}
 		}
 
-		if err := playlist.Save(); err != nil {
+		added, err := playlist.Add()
+		if err != nil {
 			return fmt.Errorf("failed to add playlist %s to watch list: %w", id, err)
 		}
+		if !added {
+			fmt.Printf("Playlist %s is already being watched\n", id)
+			return nil
+		}
 		fmt.Printf("Playlist %s added to watch list\n", id)
 
 		return nil
@@ -43,7 +108,15 @@ var watchAddCmd = &cobra.Command{
 func init() {
 	watchCmd.AddCommand(watchAddCmd)
 
-	watchAddCmd.Flags().StringVarP(&opts.Quality, "quality", "q", "mp3_320", "download quality [mp3_128, mp3_320, flac]")
-	watchAddCmd.Flags().DurationVarP(&opts.Timeout, "timeout", "t", 2*time.Minute, "timeout for each download (e.g. 10s, 1m, 2m30s)")
-	watchAddCmd.Flags().BoolVar(&opts.BPM, "bpm", false, "fetch BPM/key and add to file tags")
+	watchAddCmd.Flags().StringVarP(&watchOpts.Quality, "quality", "q", downloader.DefaultQuality, "download quality [mp3_128, mp3_320, flac]")
+	watchAddCmd.Flags().DurationVarP(&watchOpts.Timeout, "timeout", "t", downloader.DefaultTimeout, "timeout for each download (e.g. 10s, 1m, 2m30s)")
+	watchAddCmd.Flags().BoolVar(&watchOpts.BPM, "bpm", false, "fetch BPM/key and add to file tags")
+	watchAddCmd.Flags().BoolVar(&watchOpts.Genre, "genre", false, "fetch genre and add to file tags")
+	watchAddCmd.Flags().BoolVar(&watchOpts.Strict, "strict", false, "fail the song download if the quality is not available")
+	watchAddCmd.Flags().BoolVar(&watchOpts.Lyrics, "lyrics", false, "fetch lyrics, add them to file tags and write synced lyrics to .lrc files")
+	watchAddCmd.Flags().BoolVar(&watchOpts.ReplayGain, "replaygain", false, "compute the loudness of each song and add ReplayGain tags")
+	watchAddCmd.Flags().StringVarP(&watchOpts.OutputDir, "output-dir", "o", "", "output directory (default output_dir from the config)")
+	watchAddCmd.Flags().StringVar(&watchOpts.Template, "template", "", "filename template (default template from the config)")
+	watchAddCmd.Flags().StringVar(&mirrorPolicy, "mirror", store.MirrorKeep, "what to do with files of songs removed from the playlist [keep, archive, delete]")
+	watchAddCmd.Flags().BoolVarP(&mirrorYes, "yes", "y", false, "set the mirror policy without asking for confirmation")
 }
@@ -3,15 +3,18 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"text/tabwriter"
+	"time"
 
 	"github.com/mathismqn/godeez/internal/store"
 	"github.com/spf13/cobra"
 )
 
 var watchListCmd = &cobra.Command{
-	Use:   "list",
-	Short: "List watched playlists",
+	Use:     "list",
+	Short:   "List watched playlists",
+	PreRunE: openStore,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		playlists, err := store.ListWatchedPlaylists()
 		if err != nil {
@@ -24,11 +27,41 @@ var watchListCmd = &cobra.Command{
 		}
 
 		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tQuality\tFetch BPM\tTimeout")
-		fmt.Fprintln(w, "---\t-------\t----------\t-------")
+		fmt.Fprintln(w, "ID\tProfile\tQuality\tFetch BPM\tFetch Genre\tLyrics\tReplayGain\tStrict\tTimeout\tOutput Dir\tTemplate\tMirror\tTracks\tLast Sync\tAdded\tRemoved")
+		fmt.Fprintln(w, "---\t-------\t-------\t---------\t-----------\t------\t----------\t------\t-------\t----------\t--------\t------\t------\t---------\t-----\t-------")
 
 		for _, playlist := range playlists {
-			fmt.Fprintf(w, "%s\t%s\t%t\t%s\n", playlist.ID, playlist.Quality, playlist.BPM, playlist.Timeout)
+			snapshot, err := store.GetPlaylistSnapshot(playlist.Profile, playlist.ID)
+			if err != nil {
+				return fmt.Errorf("failed to get snapshot of playlist %s: %w", playlist.ID, err)
+			}
+
+			tracks, lastSync, added, removed := "-", "never", "-", "-"
+			if snapshot != nil {
+				tracks = strconv.Itoa(len(snapshot.SongIDs))
+				lastSync = snapshot.SyncedAt.Format(time.DateTime)
+				added = strconv.Itoa(snapshot.Added)
+				removed = strconv.Itoa(len(snapshot.Removed))
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%t\t%t\t%t\t%t\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				playlist.ID,
+				orDefault(playlist.Profile),
+				playlist.Quality,
+				playlist.BPM,
+				playlist.Genre,
+				playlist.Lyrics,
+				playlist.ReplayGain,
+				playlist.Strict,
+				playlist.Timeout,
+				orDefault(playlist.OutputDir),
+				orDefault(playlist.Template),
+				playlist.MirrorPolicy(),
+				tracks,
+				lastSync,
+				added,
+				removed,
+			)
 		}
 		w.Flush()
 
@@ -39,3 +72,11 @@ var watchListCmd = &cobra.Command{
 func init() {
 	watchCmd.AddCommand(watchListCmd)
 }
+
+func orDefault(value string) string {
+	if value == "" {
+		return "(default)"
+	}
+
+	return value
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/store"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mirrorDryRun bool
+	mirrorYes    bool
+)
+
+var watchMirrorCmd = &cobra.Command{
+	Use:   "mirror <playlist_id> <keep|archive|delete>",
+	Short: "Set what happens to files of songs removed from a watched playlist",
+	Long: `Set what happens to files of songs removed from a watched playlist.
+
+The watcher only archives or deletes files that were downloaded for this
+playlist and are not part of another watched playlist. Archived files are
+moved to the Archive folder of the output directory.
+
+The actions that the new policy would take right now are printed first and
+archiving or deleting asks for confirmation; use --dry-run to only print
+them and --yes to skip the confirmation. The watcher acts on a removed song
+one sync after it is removed, so a song put back in the meantime is kept.`,
+	Args:    cobra.ExactArgs(2),
+	PreRunE: openStore,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig, _ := cmd.Context().Value("appConfig").(*config.Config)
+
+		id := args[0]
+		policy := strings.ToLower(args[1])
+		if err := validateMirrorPolicy(policy); err != nil {
+			return err
+		}
+
+		playlist, err := store.GetWatchedPlaylist(appConfig.Profile, id)
+		if err != nil {
+			return err
+		}
+
+		outputDir := playlist.OutputDir
+		if outputDir == "" {
+			profileConfig, err := appConfig.WithProfile(playlist.Profile)
+			if err != nil {
+				return err
+			}
+			outputDir = profileConfig.OutputDir
+		}
+
+		if err := printMirrorPlan(cmd, playlist, policy, outputDir); err != nil {
+			return err
+		}
+
+		if mirrorDryRun {
+			fmt.Println("Dry run: no changes made")
+			return nil
+		}
+
+		ok, err := confirmMirror(cmd, id, policy, mirrorYes)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Mirror policy left unchanged")
+			return nil
+		}
+
+		setPolicy := func(p *store.WatchedPlaylist) { p.Mirror = policy }
+		if err := store.UpdateWatchedPlaylist(appConfig.Profile, id, setPolicy); err != nil {
+			return fmt.Errorf("failed to update playlist %s: %w", id, err)
+		}
+		fmt.Printf("Mirror policy of playlist %s set to %s, applied on the next sync\n", id, policy)
+
+		return nil
+	},
+}
+
+func init() {
+	watchCmd.AddCommand(watchMirrorCmd)
+
+	watchMirrorCmd.Flags().BoolVar(&mirrorDryRun, "dry-run", false, "only print what the policy would do")
+	watchMirrorCmd.Flags().BoolVarP(&mirrorYes, "yes", "y", false, "set the policy without asking for confirmation")
+}
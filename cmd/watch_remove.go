@@ -3,17 +3,21 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/mathismqn/godeez/internal/config"
 	"github.com/mathismqn/godeez/internal/store"
 	"github.com/spf13/cobra"
 )
 
 var watchRemoveCmd = &cobra.Command{
-	Use:   "remove <playlist_id>",
-	Short: "Remove a playlist from the watch list",
-	Args:  cobra.ExactArgs(1),
+	Use:     "remove <playlist_id>",
+	Short:   "Remove a playlist from the watch list",
+	Args:    cobra.ExactArgs(1),
+	PreRunE: openStore,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		appConfig, _ := cmd.Context().Value("appConfig").(*config.Config)
+
 		id := args[0]
-		ok, err := store.IsWatched(id)
+		ok, err := store.IsWatched(appConfig.Profile, id)
 		if err != nil {
 			return err
 		}
@@ -21,7 +25,7 @@ var watchRemoveCmd = &cobra.Command{
 			return fmt.Errorf("playlist %s is not being watched", id)
 		}
 
-		if err := store.RemoveWatchedPlaylist(id); err != nil {
+		if err := store.RemoveWatchedPlaylist(appConfig.Profile, id); err != nil {
 			return fmt.Errorf("failed to remove playlist %s from watch list: %w", id, err)
 		}
 		fmt.Printf("Playlist %s removed from watch list\n", id)
@@ -1,31 +1,55 @@
 package cmd
 
 import (
-	"context"
+	"errors"
+	"time"
 
 	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/store"
 	"github.com/mathismqn/godeez/internal/watcher"
 	"github.com/spf13/cobra"
 )
 
+// lockRetryInterval is how often the watcher retries to take ownership of
+// the database while another command is using it directly.
+const lockRetryInterval = 10 * time.Second
+
 var watchRunCmd = &cobra.Command{
 	Use:    "run",
 	Short:  "Start the background playlist watcher",
 	Hidden: true,
-	PreRun: func(cmd *cobra.Command, args []string) {
-		appConfig, err := config.New("")
-		if err != nil {
-			return
-		}
-		cmd.SetContext(context.WithValue(cmd.Context(), "appConfig", appConfig))
-	},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
 		ctx := cmd.Context()
-		appConfigVal := ctx.Value("appConfig")
-		appConfig, _ := appConfigVal.(*config.Config)
+
+		var appConfig *config.Config
+		for {
+			var err error
+			appConfig, err = config.New(cfgPath, profileName)
+			if err == nil {
+				break
+			}
+
+			var locked *store.LockedError
+			if !errors.As(err, &locked) {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(lockRetryInterval):
+			}
+		}
+		defer store.Close()
+
+		if err := store.Serve(ctx); err != nil {
+			return err
+		}
 
 		w := watcher.New(appConfig)
-		w.Run(ctx, opts)
+		w.Run(ctx)
+
+		return nil
 	},
 }
 
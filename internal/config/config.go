@@ -1,51 +1,184 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
-	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/mathismqn/godeez/internal/fileutil"
 	"github.com/mathismqn/godeez/internal/store"
+	"github.com/mathismqn/godeez/internal/template"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	ArlCookie string `mapstructure:"arl_cookie"`
-	SecretKey string `mapstructure:"secret_key"`
-	OutputDir string `mapstructure:"output_dir"`
-	HomeDir   string
+	ArlCookie string    `mapstructure:"arl_cookie"`
+	SecretKey string    `mapstructure:"secret_key"`
+	OutputDir string    `mapstructure:"output_dir"`
+	Template  string    `mapstructure:"template"`
+	Endpoints Endpoints `mapstructure:"endpoints"`
+	Download  Download  `mapstructure:"download"`
+	// DefaultProfile is the profile used when none is selected
+	DefaultProfile string             `mapstructure:"default_profile"`
+	Profiles       map[string]Profile `mapstructure:"profiles"`
+	// Profile is the name of the selected profile, empty when the top-level
+	// values are used
+	Profile string `mapstructure:"-"`
+	HomeDir string
+
+	// base holds the top-level values of the config file, which the
+	// selected profile overrides
+	base Profile
 }
 
-func New(cfgPath string) (*Config, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get home directory: %w", err)
+// Profile is a named set of values overriding the top-level ones, typically
+// to use another Deezer account.
+type Profile struct {
+	ArlCookie string   `mapstructure:"arl_cookie"`
+	OutputDir string   `mapstructure:"output_dir"`
+	Template  string   `mapstructure:"template"`
+	Download  Download `mapstructure:"download"`
+}
+
+// Download holds the default options of the download commands, used when
+// their flag is not given. Unset values are nil, in which case the default
+// of the flag is used.
+type Download struct {
+	Quality      *string        `mapstructure:"quality"`
+	Timeout      *time.Duration `mapstructure:"timeout"`
+	Concurrency  *int           `mapstructure:"concurrency"`
+	Retries      *int           `mapstructure:"retries"`
+	RetryBackoff *time.Duration `mapstructure:"retry_backoff"`
+	BPM          *bool          `mapstructure:"bpm"`
+	Genre        *bool          `mapstructure:"genre"`
+	Strict       *bool          `mapstructure:"strict"`
+	Lyrics       *bool          `mapstructure:"lyrics"`
+	ReplayGain   *bool          `mapstructure:"replaygain"`
+	Limit        *int           `mapstructure:"limit"`
+	Output       *string        `mapstructure:"output"`
+}
+
+// envKeys are the keys that can be set with an environment variable, named
+// after the key in upper case with dots replaced by underscores, e.g.
+// DOWNLOAD_QUALITY. Environment variables take precedence over the config
+// file, including its profiles.
+var envKeys = []string{
+	"arl_cookie",
+	"secret_key",
+	"output_dir",
+	"template",
+	"download.quality",
+	"download.timeout",
+	"download.concurrency",
+	"download.retries",
+	"download.retry_backoff",
+	"download.bpm",
+	"download.genre",
+	"download.strict",
+	"download.lyrics",
+	"download.replaygain",
+	"download.limit",
+	"download.output",
+}
+
+var envKeyReplacer = strings.NewReplacer(".", "_")
+
+// envSet reports whether the environment variable of a key is set.
+func envSet(key string) bool {
+	_, ok := os.LookupEnv(strings.ToUpper(envKeyReplacer.Replace(key)))
+
+	return ok
+}
+
+// Endpoints are the base URLs of the services godeez talks to. They only
+// need to be changed to go through a proxy or to use a fake server.
+type Endpoints struct {
+	Deezer  string `mapstructure:"deezer"`
+	Media   string `mapstructure:"media"`
+	Images  string `mapstructure:"images"`
+	SongBPM string `mapstructure:"songbpm"`
+	LastFM  string `mapstructure:"lastfm"`
+}
+
+// DefaultEndpoints are the endpoints of the real services.
+var DefaultEndpoints = Endpoints{
+	Deezer:  "https://www.deezer.com",
+	Media:   "https://media.deezer.com",
+	Images:  "https://e-cdn-images.dzcdn.net",
+	SongBPM: "https://songbpm.com",
+	LastFM:  "https://www.last.fm",
+}
+
+// WithDefaults returns the endpoints with the unset ones replaced by the
+// default ones, and without trailing slashes.
+func (e Endpoints) WithDefaults() Endpoints {
+	for _, field := range []struct {
+		value    *string
+		fallback string
+	}{
+		{&e.Deezer, DefaultEndpoints.Deezer},
+		{&e.Media, DefaultEndpoints.Media},
+		{&e.Images, DefaultEndpoints.Images},
+		{&e.SongBPM, DefaultEndpoints.SongBPM},
+		{&e.LastFM, DefaultEndpoints.LastFM},
+	} {
+		if *field.value == "" {
+			*field.value = field.fallback
+		}
+		*field.value = strings.TrimRight(*field.value, "/")
 	}
 
-	cfgDir := filepath.Join(homeDir, ".godeez")
-	if err := fileutil.EnsureDir(cfgDir); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	return e
+}
+
+// ErrNotFound is returned when the config file does not exist.
+var ErrNotFound = errors.New("config file not found")
+
+// Path returns the path of the config file: cfgPath when it is set, and
+// ~/.godeez/config.toml otherwise.
+func Path(cfgPath string) (string, error) {
+	if cfgPath != "" {
+		return cfgPath, nil
 	}
 
-	if cfgPath == "" {
-		cfgPath = path.Join(cfgDir, "config.toml")
-		if _, err := os.Stat(cfgPath); os.IsNotExist(err) {
-			fmt.Printf("Config file not found, creating one at %s\n", cfgPath)
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
 
-			content := []byte("arl_cookie = ''\nsecret_key = ''\noutput_dir = ''\n")
-			if err := os.WriteFile(cfgPath, content, 0644); err != nil {
-				return nil, fmt.Errorf("failed to create config file: %w", err)
-			}
+	return filepath.Join(homeDir, ".godeez", "config.toml"), nil
+}
 
-			os.Exit(0)
-		}
+// Load reads the config file and the environment, without selecting a
+// profile nor validating the values.
+func Load(cfgPath string) (*Config, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get home directory: %w", err)
+	}
+
+	cfgPath, err = Path(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(cfgPath); errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w at %s, run \"godeez config init\" to create it", ErrNotFound, cfgPath)
 	}
 
 	viper.SetConfigFile(cfgPath)
 	viper.SetConfigType("toml")
+	viper.SetEnvKeyReplacer(envKeyReplacer)
 	viper.AutomaticEnv()
+	for _, key := range envKeys {
+		// Bound keys are read from the environment even when they are
+		// missing from the file
+		viper.BindEnv(key)
+	}
 	if err := viper.ReadInConfig(); err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
@@ -54,30 +187,155 @@ func New(cfgPath string) (*Config, error) {
 	if err := viper.Unmarshal(cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
-	if err := cfg.Validate(); err != nil {
-		return nil, fmt.Errorf("invalid config: %w", err)
+
+	return cfg, nil
+}
+
+// New loads the config file, selects a profile, or the default profile when
+// profile is empty, and opens the database.
+func New(cfgPath, profile string) (*Config, error) {
+	file, err := Load(cfgPath)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := store.OpenDB(cfgDir); err != nil {
+	cfg, err := file.WithProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	cfgDir := filepath.Join(cfg.HomeDir, ".godeez")
+	if err := fileutil.EnsureDir(cfgDir); err != nil {
+		return nil, fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := store.OpenDB(cfgDir, file.DefaultProfile); err != nil {
 		return nil, err
 	}
 
 	return cfg, nil
 }
 
-func (c *Config) Validate() error {
-	if c.ArlCookie == "" {
-		return fmt.Errorf("arl_cookie is not set")
+// WithProfile returns a validated copy of the config using the values of a
+// profile, or of the default profile when name is empty.
+func (c *Config) WithProfile(name string) (*Config, error) {
+	cfg, err := c.SelectProfile(name)
+	if err != nil {
+		return nil, err
 	}
-	if c.SecretKey == "" {
-		return fmt.Errorf("secret_key is not set")
+
+	if err := cfg.Validate(); err != nil {
+		if cfg.Profile != "" {
+			return nil, fmt.Errorf("invalid config for profile %s: %w", cfg.Profile, err)
+		}
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// SelectProfile returns a copy of the config using the values of a profile,
+// or of the default profile when name is empty, without validating it.
+// Values missing from the profile are taken from the top level of the
+// config file.
+func (c *Config) SelectProfile(name string) (*Config, error) {
+	if name == "" {
+		name = c.DefaultProfile
 	}
-	if len(c.SecretKey) != 16 {
-		return fmt.Errorf("secret_key must be 16 bytes long")
+	// Viper lowercases the keys of the config file
+	name = strings.ToLower(name)
+
+	base := c.base
+	if c.Profile == "" {
+		base = Profile{ArlCookie: c.ArlCookie, OutputDir: c.OutputDir, Template: c.Template, Download: c.Download}
 	}
+
+	cfg := *c
+	cfg.Profile = name
+	cfg.base = base
+	cfg.ArlCookie = base.ArlCookie
+	cfg.OutputDir = base.OutputDir
+	cfg.Template = base.Template
+	cfg.Download = base.Download
+
+	if name != "" {
+		profile, ok := c.Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(c.ProfileNames(), ", "))
+		}
+		override(&cfg.ArlCookie, profile.ArlCookie, "arl_cookie")
+		override(&cfg.OutputDir, profile.OutputDir, "output_dir")
+		override(&cfg.Template, profile.Template, "template")
+
+		d, p := &cfg.Download, profile.Download
+		override(&d.Quality, p.Quality, "download.quality")
+		override(&d.Timeout, p.Timeout, "download.timeout")
+		override(&d.Concurrency, p.Concurrency, "download.concurrency")
+		override(&d.Retries, p.Retries, "download.retries")
+		override(&d.RetryBackoff, p.RetryBackoff, "download.retry_backoff")
+		override(&d.BPM, p.BPM, "download.bpm")
+		override(&d.Genre, p.Genre, "download.genre")
+		override(&d.Strict, p.Strict, "download.strict")
+		override(&d.Lyrics, p.Lyrics, "download.lyrics")
+		override(&d.ReplayGain, p.ReplayGain, "download.replaygain")
+		override(&d.Limit, p.Limit, "download.limit")
+		override(&d.Output, p.Output, "download.output")
+	}
+
+	return &cfg, nil
+}
+
+// override replaces a top-level value with the value of a profile, unless
+// the profile does not set it or the environment variable of key is set.
+func override[T comparable](dst *T, value T, key string) {
+	var unset T
+	if value != unset && !envSet(key) {
+		*dst = value
+	}
+}
+
+// ProfileNames returns the names of the profiles, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Validate fills in the defaults of the unset values and checks the
+// config.
+func (c *Config) Validate() error {
 	if c.OutputDir == "" {
 		c.OutputDir = filepath.Join(c.HomeDir, "Music", "GoDeez")
 	}
+	c.Endpoints = c.Endpoints.WithDefaults()
+
+	if errs := c.Check(); len(errs) > 0 {
+		return errs[0]
+	}
 
 	return nil
 }
+
+// Check returns every problem of the config, whereas Validate stops at the
+// first one.
+func (c *Config) Check() []error {
+	var errs []error
+	if c.ArlCookie == "" {
+		errs = append(errs, fmt.Errorf("arl_cookie is not set"))
+	}
+	if c.SecretKey == "" {
+		errs = append(errs, fmt.Errorf("secret_key is not set"))
+	} else if len(c.SecretKey) != 16 {
+		errs = append(errs, fmt.Errorf("secret_key must be 16 bytes long, got %d", len(c.SecretKey)))
+	}
+	if c.Template != "" {
+		if _, err := template.Parse(c.Template); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
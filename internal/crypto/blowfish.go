@@ -34,3 +34,18 @@ func Decrypt(data, key []byte) ([]byte, error) {
 
 	return decrypted, nil
 }
+
+// Encrypt is the inverse of Decrypt. Deezer only encrypts streams, it is used
+// to serve streams from a fake CDN.
+func Encrypt(data, key []byte) ([]byte, error) {
+	block, err := blowfish.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	mode := cipher.NewCBCEncrypter(block, iv)
+	encrypted := make([]byte, len(data))
+	mode.CryptBlocks(encrypted, data)
+
+	return encrypted, nil
+}
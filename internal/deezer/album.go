@@ -3,27 +3,27 @@ package deezer
 import (
 	"encoding/json"
 	"fmt"
-	"path"
 	"strconv"
 	"time"
-
-	"github.com/flytam/filenamify"
 )
 
 type Album struct {
 	Results struct {
 		Data struct {
-			Title               string `json:"ALB_TITLE"`
-			Artist              string `json:"ART_NAME"`
-			OriginalReleaseDate string `json:"ORIGINAL_RELEASE_DATE"`
-			PhysicalReleaseDate string `json:"PHYSICAL_RELEASE_DATE"`
-			Label               string `json:"LABEL_NAME"`
-			ProducerLine        string `json:"PRODUCER_LINE"`
-			Copyright           string `json:"COPYRIGHT"`
-			Duration            string `json:"DURATION"`
+			Title               string      `json:"ALB_TITLE"`
+			Artist              string      `json:"ART_NAME"`
+			OriginalReleaseDate string      `json:"ORIGINAL_RELEASE_DATE"`
+			PhysicalReleaseDate string      `json:"PHYSICAL_RELEASE_DATE"`
+			Label               string      `json:"LABEL_NAME"`
+			ProducerLine        string      `json:"PRODUCER_LINE"`
+			Copyright           string      `json:"COPYRIGHT"`
+			Duration            string      `json:"DURATION"`
+			TrackCount          json.Number `json:"NUMBER_TRACK"`
+			DiskCount           json.Number `json:"NUMBER_DISK"`
 		} `json:"DATA"`
 		Songs struct {
-			Data []*Song `json:"data"`
+			Data  []*Song `json:"data"`
+			Total int     `json:"total"`
 		} `json:"SONGS"`
 	} `json:"results"`
 }
@@ -43,7 +43,7 @@ Duration: %s
 ==================================================`,
 		a.Results.Data.Title,
 		a.Results.Data.Artist,
-		len(a.Results.Songs.Data),
+		a.GetTotalSongs(),
 		time.Duration(duration)*time.Second,
 	)
 }
@@ -64,12 +64,42 @@ func (a *Album) SetSongs(s []*Song) {
 	a.Results.Songs.Data = s
 }
 
-func (a *Album) GetOutputDir(outputDir string) string {
-	base := fmt.Sprintf("%s - %s", a.Results.Data.Artist, a.Results.Data.Title)
-	base, _ = filenamify.Filenamify(base, filenamify.Options{})
-	outputDir = path.Join(outputDir, base)
+func (a *Album) GetTotalSongs() int {
+	return max(a.Results.Songs.Total, len(a.Results.Songs.Data))
+}
+
+// TotalTracks returns the number of tracks of the album, or an empty string
+// when it is unknown.
+func (a *Album) TotalTracks() string {
+	if n, err := a.Results.Data.TrackCount.Int64(); err == nil && n > 0 {
+		return strconv.FormatInt(n, 10)
+	}
+	if total := a.GetTotalSongs(); total > 0 {
+		return strconv.Itoa(total)
+	}
+
+	return ""
+}
+
+// TotalDiscs returns the number of discs of the album, or an empty string
+// when it is unknown. Without the count from Deezer, it is the highest disc
+// number of the fetched songs.
+func (a *Album) TotalDiscs() string {
+	if n, err := a.Results.Data.DiskCount.Int64(); err == nil && n > 0 {
+		return strconv.FormatInt(n, 10)
+	}
+
+	discs := 0
+	for _, song := range a.GetSongs() {
+		if n, err := strconv.Atoi(song.DiskNumber); err == nil {
+			discs = max(discs, n)
+		}
+	}
+	if discs == 0 {
+		return ""
+	}
 
-	return outputDir
+	return strconv.Itoa(discs)
 }
 
 func (a *Album) Unmarshal(data []byte) error {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -17,8 +18,11 @@ type Client struct {
 	Session   *Session
 }
 
-func NewClient(ctx context.Context, appConfig *config.Config) (*Client, error) {
-	session, err := Authenticate(ctx, appConfig.ArlCookie)
+func NewClient(ctx context.Context, appConfig *config.Config, retry RetryPolicy) (*Client, error) {
+	session, err := AuthenticateWithOptions(ctx, appConfig.ArlCookie, SessionOptions{
+		Retry:     retry,
+		Endpoints: appConfig.Endpoints,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to authenticate: %w", err)
 	}
@@ -29,16 +33,48 @@ func NewClient(ctx context.Context, appConfig *config.Config) (*Client, error) {
 	}, nil
 }
 
+// FetchResource fetches a resource with all of its songs.
 func (c *Client) FetchResource(ctx context.Context, resource Resource, id string) error {
+	pages, err := c.FetchResourcePages(ctx, resource, id)
+	if err != nil {
+		return err
+	}
+
+	for {
+		songs, err := pages.Next(ctx)
+		if err != nil {
+			return err
+		}
+		if songs == nil {
+			return nil
+		}
+	}
+}
+
+// FetchAlbum fetches the details of an album, with its first page of songs
+// only.
+func (c *Client) FetchAlbum(ctx context.Context, id string) (*Album, error) {
+	album := &Album{}
+	if err := c.fetchResourcePage(ctx, album, id, 0); err != nil {
+		return nil, err
+	}
+
+	return album, nil
+}
+
+// fetchResourcePage fetches the resource with the songs starting at start.
+func (c *Client) fetchResourcePage(ctx context.Context, resource Resource, id string, start int) error {
 	payload := map[string]interface{}{
-		"nb":     10000,
-		"start":  0,
+		"nb":     pageSize,
+		"start":  start,
 		"lang":   "en",
 		"tab":    0,
 		"tags":   true,
 		"header": true,
 	}
 	switch r := resource.(type) {
+	case *Favorites:
+		payload["playlist_id"] = c.Session.LovedTracksID
 	case *Playlist:
 		payload["playlist_id"] = id
 	case *Album:
@@ -51,51 +87,118 @@ func (c *Client) FetchResource(ctx context.Context, resource Resource, id string
 		return fmt.Errorf("unsupported resource type: %T", r)
 	}
 
-	jsonData, err := json.Marshal(payload)
+	body, err := c.callGateway(ctx, "deezer.page"+resource.GetType(), payload)
 	if err != nil {
 		return err
 	}
 
-	url := fmt.Sprintf("https://www.deezer.com/ajax/gw-light.php?method=deezer.page%s&input=3&api_version=1.0&api_token=%s", resource.GetType(), c.Session.APIToken)
+	switch {
+	case strings.Contains(string(body), `"DATA_ERROR":"playlist::getData"`):
+		return fmt.Errorf("invalid playlist ID")
+	case strings.Contains(string(body), `"DATA_ERROR":"album::getData"`):
+		return fmt.Errorf("invalid album ID")
+	case strings.Contains(string(body), `"DATA_ERROR":"artist::getData"`):
+		return fmt.Errorf("invalid artist ID")
+	case strings.Contains(string(body), `"DATA_ERROR":"song::getData"`):
+		return fmt.Errorf("invalid track ID")
+	}
+
+	if strings.Contains(string(body), `"results":{}`) {
+		return fmt.Errorf("unexpected response")
+	}
+
+	return resource.Unmarshal(body)
+}
+
+// errInvalidLicenseToken is returned by get_url when the license token of
+// the session expired.
+var errInvalidLicenseToken = errors.New("invalid license token")
+
+// callGateway calls a gw-light method and returns the raw response body.
+// When the API token of the session expired, the session is refreshed and
+// the call is made again.
+func (c *Client) callGateway(ctx context.Context, method string, payload any) ([]byte, error) {
+	tokens := c.Session.tokens()
+	body, err := c.postGateway(ctx, method, payload, tokens.apiToken)
+	if err != nil || !isTokenError(body) {
+		return body, err
+	}
+
+	if err := c.Session.refresh(ctx, tokens); err != nil {
+		return nil, err
+	}
+
+	return c.postGateway(ctx, method, payload, c.Session.tokens().apiToken)
+}
+
+func (c *Client) postGateway(ctx context.Context, method string, payload any, apiToken string) ([]byte, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	url := fmt.Sprintf("%s/ajax/gw-light.php?method=%s&input=3&api_version=1.0&api_token=%s", c.Session.Endpoints.Deezer, method, apiToken)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	resp, err := c.Session.HttpClient.Do(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return err
-	}
+	return io.ReadAll(resp.Body)
+}
 
-	switch {
-	case strings.Contains(string(body), `"DATA_ERROR":"playlist::getData"`):
-		return fmt.Errorf("invalid playlist ID")
-	case strings.Contains(string(body), `"DATA_ERROR":"album::getData"`):
-		return fmt.Errorf("invalid album ID")
-	case strings.Contains(string(body), `"DATA_ERROR":"artist::getData"`):
-		return fmt.Errorf("invalid artist ID")
-	case strings.Contains(string(body), `"DATA_ERROR":"song::getData"`):
-		return fmt.Errorf("invalid track ID")
+// gatewayError returns the error of a gw-light response, or an empty string
+// when the call succeeded.
+func gatewayError(body []byte) string {
+	var resp struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return ""
 	}
 
-	if strings.Contains(string(body), `"results":{}`) {
-		return fmt.Errorf("unexpected response")
+	switch string(resp.Error) {
+	case "", "[]", "{}", "null":
+		return ""
+	default:
+		return string(resp.Error)
 	}
+}
 
-	return resource.Unmarshal(body)
+// isTokenError reports whether a gw-light call failed because the API token
+// expired.
+func isTokenError(body []byte) bool {
+	msg := strings.ToLower(gatewayError(body))
+
+	return strings.Contains(msg, "valid_token_required") || strings.Contains(msg, "invalid api token")
 }
 
+// FetchMedia returns the media URLs of a song. When the license token of the
+// session expired, the session is refreshed and the request is made again.
 func (c *Client) FetchMedia(ctx context.Context, song *Song, quality string) (*Media, error) {
+	tokens := c.Session.tokens()
+	media, err := c.fetchMedia(ctx, song, quality, tokens.licenseToken)
+	if !errors.Is(err, errInvalidLicenseToken) {
+		return media, err
+	}
+
+	if err := c.Session.refresh(ctx, tokens); err != nil {
+		return nil, err
+	}
+
+	return c.fetchMedia(ctx, song, quality, c.Session.tokens().licenseToken)
+}
+
+func (c *Client) fetchMedia(ctx context.Context, song *Song, quality, licenseToken string) (*Media, error) {
 	var formats string
 
 	switch quality {
@@ -107,8 +210,8 @@ func (c *Client) FetchMedia(ctx context.Context, song *Song, quality string) (*M
 		formats = `[{"cipher":"BF_CBC_STRIPE","format":"FLAC"},{"cipher":"BF_CBC_STRIPE","format":"MP3_320"},{"cipher":"BF_CBC_STRIPE","format":"MP3_128"}]`
 	}
 
-	reqBody := fmt.Sprintf(`{"license_token":"%s","media":[{"type":"FULL","formats":%s}],"track_tokens":["%s"]}`, c.Session.LicenseToken, formats, song.TrackToken)
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://media.deezer.com/v1/get_url", bytes.NewBuffer([]byte(reqBody)))
+	reqBody := fmt.Sprintf(`{"license_token":"%s","media":[{"type":"FULL","formats":%s}],"track_tokens":["%s"]}`, licenseToken, formats, song.TrackToken)
+	req, err := http.NewRequestWithContext(ctx, "POST", c.Session.Endpoints.Media+"/v1/get_url", bytes.NewBuffer([]byte(reqBody)))
 	if err != nil {
 		return nil, err
 	}
@@ -136,7 +239,7 @@ func (c *Client) FetchMedia(ctx context.Context, song *Song, quality string) (*M
 
 	if len(media.Errors) > 0 {
 		if media.Errors[0].Code == 1000 {
-			return nil, fmt.Errorf("invalid license token")
+			return nil, errInvalidLicenseToken
 		}
 
 		return nil, fmt.Errorf("%s", media.Errors[0].Message)
@@ -158,7 +261,7 @@ func (c *Client) FetchMedia(ctx context.Context, song *Song, quality string) (*M
 }
 
 func (c *Client) FetchCoverImage(ctx context.Context, song *Song) ([]byte, error) {
-	url := fmt.Sprintf("https://e-cdn-images.dzcdn.net/images/cover/%s/500x500-000000-80-0-0.jpg", song.Cover)
+	url := fmt.Sprintf("%s/images/cover/%s/500x500-000000-80-0-0.jpg", c.Session.Endpoints.Images, song.Cover)
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -177,24 +280,45 @@ func (c *Client) FetchCoverImage(ctx context.Context, song *Song) ([]byte, error
 	return io.ReadAll(resp.Body)
 }
 
-func (c *Client) GetMediaStream(ctx context.Context, media *Media, songID string) (io.ReadCloser, error) {
+// ErrRangeNotSatisfiable is returned by GetMediaStream when the CDN rejects
+// the requested resume offset.
+var ErrRangeNotSatisfiable = errors.New("range not satisfiable")
+
+// GetMediaStream opens the encrypted media stream, starting at offset bytes
+// when resuming a partial download.
+func (c *Client) GetMediaStream(ctx context.Context, media *Media, songID string, offset int64) (io.ReadCloser, error) {
 	url := media.GetURL()
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	req, err := http.NewRequestWithContext(withStreaming(ctx), "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
 
-	streamingClient := *c.Session.HttpClient
-	streamingClient.Timeout = 0
-
-	resp, err := streamingClient.Do(req)
+	resp, err := c.Session.HttpClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.StatusCode != http.StatusOK {
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return resp.Body, nil
+	case http.StatusOK:
+		if offset > 0 {
+			// The server ignored the range, skip what we already have
+			if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+				resp.Body.Close()
+				return nil, err
+			}
+		}
+
+		return resp.Body, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return nil, ErrRangeNotSatisfiable
+	default:
+		resp.Body.Close()
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
-
-	return resp.Body, nil
 }
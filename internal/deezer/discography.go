@@ -0,0 +1,107 @@
+package deezer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+const discographyPageSize = 100
+
+// ReleaseTypes lists the release types of discography entries, indexed by
+// their TYPE field.
+var ReleaseTypes = []string{"single", "album", "compilation", "ep", "bundle"}
+
+// roleMainArtist is the ROLE_ID of releases credited to the artist, as
+// opposed to releases the artist is featured on.
+const roleMainArtist = "0"
+
+// Release is an entry of an artist's discography.
+type Release struct {
+	ID                  string      `json:"ALB_ID"`
+	Title               string      `json:"ALB_TITLE"`
+	Artist              string      `json:"ART_NAME"`
+	Type                json.Number `json:"TYPE"`
+	RoleID              json.Number `json:"ROLE_ID"`
+	OriginalReleaseDate string      `json:"ORIGINAL_RELEASE_DATE"`
+	PhysicalReleaseDate string      `json:"PHYSICAL_RELEASE_DATE"`
+}
+
+// ReleaseType returns album, single, ep, compilation or bundle.
+func (r *Release) ReleaseType() string {
+	i, err := r.Type.Int64()
+	if err != nil || i < 0 || int(i) >= len(ReleaseTypes) {
+		return "album"
+	}
+
+	return ReleaseTypes[i]
+}
+
+// ReleaseDate returns the release date as YYYY-MM-DD, or an empty string
+// when unknown.
+func (r *Release) ReleaseDate() string {
+	date := r.OriginalReleaseDate
+	if date == "" || date == "0000-00-00" {
+		date = r.PhysicalReleaseDate
+	}
+	if date == "0000-00-00" {
+		return ""
+	}
+
+	return date
+}
+
+type discographyResponse struct {
+	Results struct {
+		Data  []*Release `json:"data"`
+		Total int        `json:"total"`
+	} `json:"results"`
+}
+
+// FetchDiscography returns the releases credited to an artist, fetching
+// the discography page by page.
+func (c *Client) FetchDiscography(ctx context.Context, artistID string) ([]*Release, error) {
+	var releases []*Release
+	seen := make(map[string]bool)
+
+	for start := 0; ; start += discographyPageSize {
+		payload := map[string]interface{}{
+			"art_id":           artistID,
+			"discography_mode": "all",
+			"nb":               discographyPageSize,
+			"nb_songs":         0,
+			"start":            start,
+		}
+
+		body, err := c.callGateway(ctx, "album.getDiscography", payload)
+		if err != nil {
+			return nil, err
+		}
+
+		if msg := gatewayError(body); msg != "" {
+			return nil, fmt.Errorf("failed to fetch discography: %s", msg)
+		}
+
+		var resp discographyResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+
+		for _, release := range resp.Results.Data {
+			if role := release.RoleID.String(); role != "" && role != roleMainArtist {
+				continue
+			}
+			if seen[release.ID] {
+				continue
+			}
+			seen[release.ID] = true
+			releases = append(releases, release)
+		}
+
+		if len(resp.Results.Data) == 0 || start+discographyPageSize >= resp.Results.Total {
+			break
+		}
+	}
+
+	return releases, nil
+}
@@ -0,0 +1,147 @@
+package deezer
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Favorites is the playlist of the user's favourite tracks, also known as
+// "Loved Tracks". It is fetched for the authenticated user whatever the ID.
+type Favorites struct {
+	Playlist
+}
+
+// Collection is a list of resources saved by the user, such as the albums
+// of their library.
+type Collection interface {
+	GetTitle() string
+	// GetTab returns the tab of the user's profile listing the resources
+	GetTab() string
+	GetLinks() []Link
+	// AddPage adds the resources of a page of the tab and returns how many
+	// it had
+	AddPage(data []byte) (int, error)
+}
+
+// LibraryAlbums is the list of albums saved in the user's library.
+type LibraryAlbums struct {
+	Results struct {
+		Tab struct {
+			Albums struct {
+				Data []struct {
+					ID string `json:"ALB_ID"`
+				} `json:"data"`
+			} `json:"albums"`
+		} `json:"TAB"`
+	} `json:"results"`
+}
+
+func (l *LibraryAlbums) GetTitle() string {
+	return "Library Albums"
+}
+
+func (l *LibraryAlbums) GetTab() string {
+	return "albums"
+}
+
+func (l *LibraryAlbums) GetLinks() []Link {
+	links := make([]Link, 0, len(l.Results.Tab.Albums.Data))
+	for _, album := range l.Results.Tab.Albums.Data {
+		links = append(links, Link{Type: "album", ID: album.ID})
+	}
+
+	return links
+}
+
+func (l *LibraryAlbums) AddPage(data []byte) (int, error) {
+	var page LibraryAlbums
+	if err := json.Unmarshal(data, &page); err != nil {
+		return 0, err
+	}
+	albums := page.Results.Tab.Albums.Data
+	l.Results.Tab.Albums.Data = append(l.Results.Tab.Albums.Data, albums...)
+
+	return len(albums), nil
+}
+
+// LibraryPlaylists is the list of playlists created or followed by the
+// user, except their favourite tracks.
+type LibraryPlaylists struct {
+	Results struct {
+		Tab struct {
+			Playlists struct {
+				Data []struct {
+					ID string `json:"PLAYLIST_ID"`
+				} `json:"data"`
+			} `json:"playlists"`
+		} `json:"TAB"`
+	} `json:"results"`
+
+	lovedTracksID string
+}
+
+func (l *LibraryPlaylists) GetTitle() string {
+	return "Library Playlists"
+}
+
+func (l *LibraryPlaylists) GetTab() string {
+	return "playlists"
+}
+
+func (l *LibraryPlaylists) GetLinks() []Link {
+	links := make([]Link, 0, len(l.Results.Tab.Playlists.Data))
+	for _, playlist := range l.Results.Tab.Playlists.Data {
+		if playlist.ID == l.lovedTracksID {
+			continue
+		}
+		links = append(links, Link{Type: "playlist", ID: playlist.ID})
+	}
+
+	return links
+}
+
+func (l *LibraryPlaylists) AddPage(data []byte) (int, error) {
+	var page LibraryPlaylists
+	if err := json.Unmarshal(data, &page); err != nil {
+		return 0, err
+	}
+	playlists := page.Results.Tab.Playlists.Data
+	l.Results.Tab.Playlists.Data = append(l.Results.Tab.Playlists.Data, playlists...)
+
+	return len(playlists), nil
+}
+
+// FetchCollection fetches a collection of the authenticated user, page by
+// page until a page comes back short.
+func (c *Client) FetchCollection(ctx context.Context, collection Collection) error {
+	if playlists, ok := collection.(*LibraryPlaylists); ok {
+		playlists.lovedTracksID = c.Session.LovedTracksID
+	}
+
+	for start := 0; ; start += pageSize {
+		payload := map[string]interface{}{
+			"user_id": c.Session.UserID,
+			"tab":     collection.GetTab(),
+			"nb":      pageSize,
+			"start":   start,
+		}
+
+		body, err := c.callGateway(ctx, "deezer.pageProfile", payload)
+		if err != nil {
+			return err
+		}
+
+		if msg := gatewayError(body); msg != "" {
+			return fmt.Errorf("failed to fetch %s: %s", collection.GetTab(), msg)
+		}
+
+		n, err := collection.AddPage(body)
+		if err != nil {
+			return err
+		}
+		if n < pageSize {
+			return nil
+		}
+	}
+}
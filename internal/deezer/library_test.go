@@ -0,0 +1,77 @@
+package deezer_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/deezertest"
+)
+
+func newTestClient(t *testing.T, srv *deezertest.Server) *deezer.Client {
+	t.Helper()
+
+	client, err := deezer.NewClient(context.Background(), srv.Config(t.TempDir()), deezer.DefaultRetryPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+func ids(n int, offset int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = strconv.Itoa(offset + i)
+	}
+
+	return ids
+}
+
+func TestFetchCollectionPages(t *testing.T) {
+	tests := []struct {
+		name       string
+		collection func() deezer.Collection
+		albums     int
+		playlists  int
+		wantLinks  int
+		wantCalls  int
+	}{
+		{name: "albums on one page", collection: func() deezer.Collection { return &deezer.LibraryAlbums{} }, albums: 3, wantLinks: 3, wantCalls: 1},
+		{name: "albums on several pages", collection: func() deezer.Collection { return &deezer.LibraryAlbums{} }, albums: 2500, wantLinks: 2500, wantCalls: 3},
+		{name: "albums filling the last page", collection: func() deezer.Collection { return &deezer.LibraryAlbums{} }, albums: 2000, wantLinks: 2000, wantCalls: 3},
+		{name: "no albums", collection: func() deezer.Collection { return &deezer.LibraryAlbums{} }, wantLinks: 0, wantCalls: 1},
+		// The favourite tracks are listed with the playlists but left out
+		{name: "playlists on several pages", collection: func() deezer.Collection { return &deezer.LibraryPlaylists{} }, playlists: 1200, wantLinks: 1200, wantCalls: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := deezertest.NewServer()
+			defer srv.Close()
+			srv.SetLibrary(ids(tt.albums, 1), ids(tt.playlists, 100000))
+			client := newTestClient(t, srv)
+
+			collection := tt.collection()
+			if err := client.FetchCollection(context.Background(), collection); err != nil {
+				t.Fatal(err)
+			}
+
+			links := collection.GetLinks()
+			if len(links) != tt.wantLinks {
+				t.Errorf("got %d links, want %d", len(links), tt.wantLinks)
+			}
+			seen := make(map[deezer.Link]bool)
+			for _, link := range links {
+				if seen[link] {
+					t.Fatalf("%s listed twice", link)
+				}
+				seen[link] = true
+			}
+			if calls := srv.Calls("deezer.pageProfile"); calls != tt.wantCalls {
+				t.Errorf("got %d requests, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
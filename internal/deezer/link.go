@@ -0,0 +1,151 @@
+package deezer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ResourceTypes lists the resource types that can be downloaded.
+var ResourceTypes = []string{"album", "playlist", "artist", "track"}
+
+var numericID = regexp.MustCompile(`^[0-9]+$`)
+
+// shortLinkHosts redirect to a www.deezer.com URL.
+var shortLinkHosts = map[string]bool{
+	"deezer.page.link": true,
+	"dzr.page.link":    true,
+	"link.deezer.com":  true,
+}
+
+// Link identifies a Deezer resource by its type and ID.
+type Link struct {
+	Type string
+	ID   string
+}
+
+func (l Link) String() string {
+	return l.Type + ":" + l.ID
+}
+
+// NewResource returns an empty resource of the given type.
+func NewResource(resourceType string) (Resource, error) {
+	switch resourceType {
+	case "album":
+		return &Album{}, nil
+	case "playlist":
+		return &Playlist{}, nil
+	case "artist":
+		return &Artist{}, nil
+	case "track":
+		return &Track{}, nil
+	case "favorites":
+		return &Favorites{}, nil
+	default:
+		return nil, fmt.Errorf("unsupported resource type: %s", resourceType)
+	}
+}
+
+// ParseLink parses a Deezer web URL (https://www.deezer.com/en/album/123),
+// a deezer:// URI (deezer://www.deezer.com/album/123) or a typed ID
+// (album:123). Short links must be resolved with ResolveLink.
+func ParseLink(s string) (Link, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Link{}, fmt.Errorf("empty link")
+	}
+
+	if resourceType, id, ok := strings.Cut(s, ":"); ok && isResourceType(resourceType) {
+		if !numericID.MatchString(id) {
+			return Link{}, fmt.Errorf("invalid %s ID: %s", resourceType, id)
+		}
+		return Link{Type: resourceType, ID: id}, nil
+	}
+
+	if numericID.MatchString(s) {
+		return Link{}, fmt.Errorf("missing resource type for ID %s (e.g. album:%s)", s, s)
+	}
+
+	u, err := parseURL(s)
+	if err != nil {
+		return Link{}, err
+	}
+
+	if shortLinkHosts[u.Host] {
+		return Link{}, fmt.Errorf("short link must be resolved: %s", s)
+	}
+	if u.Scheme != "deezer" && u.Host != "deezer.com" && !strings.HasSuffix(u.Host, ".deezer.com") {
+		return Link{}, fmt.Errorf("not a Deezer link: %s", s)
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if u.Scheme == "deezer" && u.Host != "" && !strings.Contains(u.Host, ".") {
+		// deezer://album/123 has the resource type as host
+		segments = append([]string{u.Host}, segments...)
+	}
+
+	for i := 0; i+1 < len(segments); i++ {
+		if isResourceType(segments[i]) && numericID.MatchString(segments[i+1]) {
+			return Link{Type: segments[i], ID: segments[i+1]}, nil
+		}
+	}
+
+	return Link{}, fmt.Errorf("no album, playlist, artist or track found in link: %s", s)
+}
+
+// ResolveLink parses a link like ParseLink, following the redirect of
+// short links such as https://deezer.page.link/abc first.
+func ResolveLink(ctx context.Context, httpClient *http.Client, s string) (Link, error) {
+	s = strings.TrimSpace(s)
+	u, err := parseURL(s)
+	if err != nil || !shortLinkHosts[u.Host] {
+		return ParseLink(s)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+	if err != nil {
+		return Link{}, err
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return Link{}, fmt.Errorf("failed to resolve short link: %w", err)
+	}
+	resp.Body.Close()
+
+	link, err := ParseLink(resp.Request.URL.String())
+	if err != nil {
+		return Link{}, fmt.Errorf("failed to resolve short link %s: %w", s, err)
+	}
+
+	return link, nil
+}
+
+// parseURL parses s as a URL, adding the https scheme when it is missing
+// so that links like www.deezer.com/album/123 are accepted.
+func parseURL(s string) (*url.URL, error) {
+	if !strings.Contains(s, "://") {
+		s = "https://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid link: %s", s)
+	}
+	u.Host = strings.ToLower(u.Host)
+
+	return u, nil
+}
+
+func isResourceType(s string) bool {
+	for _, t := range ResourceTypes {
+		if s == t {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,151 @@
+package deezer
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParseLink(t *testing.T) {
+	tests := []struct {
+		link    string
+		want    Link
+		wantErr string
+	}{
+		// Web URLs
+		{link: "https://www.deezer.com/album/302127", want: Link{"album", "302127"}},
+		{link: "https://www.deezer.com/fr/album/302127", want: Link{"album", "302127"}},
+		{link: "https://www.deezer.com/en/playlist/908622995", want: Link{"playlist", "908622995"}},
+		{link: "https://deezer.com/us/artist/27", want: Link{"artist", "27"}},
+		{link: "http://www.deezer.com/track/3135556", want: Link{"track", "3135556"}},
+		{link: "www.deezer.com/track/3135556", want: Link{"track", "3135556"}},
+		{link: "HTTPS://WWW.DEEZER.COM/album/302127", want: Link{"album", "302127"}},
+		{link: "  https://www.deezer.com/album/302127  ", want: Link{"album", "302127"}},
+		{link: "https://www.deezer.com/album/302127/", want: Link{"album", "302127"}},
+
+		// Query strings and fragments
+		{link: "https://www.deezer.com/fr/album/302127?utm_source=deezer&utm_medium=share", want: Link{"album", "302127"}},
+		{link: "https://www.deezer.com/track/3135556#lyrics", want: Link{"track", "3135556"}},
+		{link: "https://www.deezer.com/en/playlist/908622995?host=0&deferredFl=1#top", want: Link{"playlist", "908622995"}},
+
+		// deezer:// URIs
+		{link: "deezer://www.deezer.com/album/302127", want: Link{"album", "302127"}},
+		{link: "deezer://www.deezer.com/fr/playlist/908622995", want: Link{"playlist", "908622995"}},
+		{link: "deezer://album/302127", want: Link{"album", "302127"}},
+		{link: "deezer://track/3135556?autoplay=true", want: Link{"track", "3135556"}},
+
+		// Typed and bare IDs
+		{link: "album:302127", want: Link{"album", "302127"}},
+		{link: "playlist:908622995", want: Link{"playlist", "908622995"}},
+		{link: "artist:27", want: Link{"artist", "27"}},
+		{link: "track:3135556", want: Link{"track", "3135556"}},
+		{link: "album:abc", wantErr: "invalid album ID"},
+		{link: "302127", wantErr: "missing resource type"},
+
+		// Invalid links
+		{link: "", wantErr: "empty link"},
+		{link: "https://open.spotify.com/album/302127", wantErr: "not a Deezer link"},
+		{link: "https://www.notdeezer.com/album/302127", wantErr: "not a Deezer link"},
+		{link: "https://deezer.com.evil.example/album/302127", wantErr: "not a Deezer link"},
+		{link: "https://www.deezer.com/fr/show/302127", wantErr: "no album, playlist, artist or track"},
+		{link: "https://www.deezer.com/album/latest", wantErr: "no album, playlist, artist or track"},
+		{link: "https://deezer.page.link/AbCdEfGh", wantErr: "short link must be resolved"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			got, err := ParseLink(tt.link)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseLink(%q) = %v, %v, want error %q", tt.link, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLink(%q) failed: %v", tt.link, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLink(%q) = %v, want %v", tt.link, got, tt.want)
+			}
+		})
+	}
+}
+
+// hostTransport sends every request to a test server, keeping the
+// original host in the request URL.
+type hostTransport struct {
+	server *url.URL
+}
+
+func (h hostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r := req.Clone(req.Context())
+	r.URL.Scheme = h.server.Scheme
+	r.URL.Host = h.server.Host
+	r.Host = req.URL.Host
+
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	resp.Request = req
+
+	return resp, nil
+}
+
+func TestResolveLink(t *testing.T) {
+	redirects := map[string]string{
+		"deezer.page.link/AbCdEfGh": "https://www.deezer.com/fr/album/302127?host=0&utm_campaign=share",
+		"dzr.page.link/XyZ":         "https://www.deezer.com/track/3135556",
+		"link.deezer.com/s/30ABC":   "https://www.deezer.com/en/playlist/908622995#share",
+		"deezer.page.link/Spotify":  "https://open.spotify.com/album/302127",
+		"deezer.page.link/Show":     "https://www.deezer.com/fr/show/1000",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if target, ok := redirects[r.Host+r.URL.Path]; ok {
+			http.Redirect(w, r, target, http.StatusFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	server, _ := url.Parse(srv.URL)
+	client := &http.Client{Transport: hostTransport{server: server}}
+
+	tests := []struct {
+		link    string
+		want    Link
+		wantErr string
+	}{
+		{link: "https://deezer.page.link/AbCdEfGh", want: Link{"album", "302127"}},
+		{link: "deezer.page.link/AbCdEfGh", want: Link{"album", "302127"}},
+		{link: "https://dzr.page.link/XyZ", want: Link{"track", "3135556"}},
+		{link: "https://link.deezer.com/s/30ABC", want: Link{"playlist", "908622995"}},
+		{link: "https://deezer.page.link/Spotify", wantErr: "not a Deezer link"},
+		{link: "https://deezer.page.link/Show", wantErr: "failed to resolve short link"},
+		// Links that are not short links are parsed without a request
+		{link: "https://www.deezer.com/artist/27", want: Link{"artist", "27"}},
+		{link: "album:302127", want: Link{"album", "302127"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.link, func(t *testing.T) {
+			got, err := ResolveLink(context.Background(), client, tt.link)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ResolveLink(%q) = %v, %v, want error %q", tt.link, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveLink(%q) failed: %v", tt.link, err)
+			}
+			if got != tt.want {
+				t.Errorf("ResolveLink(%q) = %v, want %v", tt.link, got, tt.want)
+			}
+		})
+	}
+}
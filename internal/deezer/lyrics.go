@@ -0,0 +1,87 @@
+package deezer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoLyrics is returned by FetchLyrics when Deezer has no lyrics for a
+// song.
+var ErrNoLyrics = errors.New("no lyrics available")
+
+// Lyrics are the lyrics of a song. Synced is empty when Deezer only has the
+// plain text.
+type Lyrics struct {
+	Text   string
+	Synced []LyricsLine
+}
+
+// LyricsLine is a line of synchronised lyrics, sung Time after the start
+// of the song.
+type LyricsLine struct {
+	Time time.Duration
+	Text string
+}
+
+type lyricsResponse struct {
+	Results struct {
+		Text string `json:"LYRICS_TEXT"`
+		Sync []struct {
+			// Milliseconds is a number in a string, empty on some entries
+			Milliseconds json.RawMessage `json:"milliseconds"`
+			Line         string          `json:"line"`
+		} `json:"LYRICS_SYNC_JSON"`
+	} `json:"results"`
+}
+
+// FetchLyrics returns the lyrics of a song, or ErrNoLyrics when it has
+// none.
+func (c *Client) FetchLyrics(ctx context.Context, songID string) (*Lyrics, error) {
+	body, err := c.callGateway(ctx, "song.getLyrics", map[string]interface{}{"sng_id": songID})
+	if err != nil {
+		return nil, err
+	}
+
+	if msg := gatewayError(body); msg != "" {
+		if strings.Contains(msg, "DATA_ERROR") {
+			return nil, ErrNoLyrics
+		}
+		return nil, fmt.Errorf("failed to fetch lyrics: %s", msg)
+	}
+
+	var resp lyricsResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	lyrics := &Lyrics{Text: strings.TrimSpace(strings.ReplaceAll(resp.Results.Text, "\r\n", "\n"))}
+	for _, line := range resp.Results.Sync {
+		// Some entries only mark the end of the lyrics and have no time
+		ms, err := strconv.ParseInt(strings.Trim(string(line.Milliseconds), `"`), 10, 64)
+		if err != nil {
+			continue
+		}
+		lyrics.Synced = append(lyrics.Synced, LyricsLine{
+			Time: time.Duration(ms) * time.Millisecond,
+			Text: strings.TrimSpace(line.Line),
+		})
+	}
+
+	if lyrics.Text == "" {
+		lines := make([]string, 0, len(lyrics.Synced))
+		for _, line := range lyrics.Synced {
+			lines = append(lines, line.Text)
+		}
+		lyrics.Text = strings.TrimSpace(strings.Join(lines, "\n"))
+	}
+	if lyrics.Text == "" {
+		return nil, ErrNoLyrics
+	}
+
+	return lyrics, nil
+}
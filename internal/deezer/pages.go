@@ -0,0 +1,88 @@
+package deezer
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// pageSize is the number of songs requested per page.
+const pageSize = 1000
+
+// PagedResource is a resource whose songs are fetched page by page.
+type PagedResource interface {
+	Resource
+	// GetTotalSongs returns the number of songs of the resource, including
+	// those of pages that were not fetched yet
+	GetTotalSongs() int
+}
+
+// ResourcePages fetches the songs of a resource one page at a time,
+// merging each page into the resource.
+type ResourcePages struct {
+	client   *Client
+	resource Resource
+	id       string
+	first    []*Song
+	fetched  int
+	done     bool
+}
+
+// FetchResourcePages fetches a resource with its first page of songs. The
+// songs of every page, including the first one, are returned by Next.
+func (c *Client) FetchResourcePages(ctx context.Context, resource Resource, id string) (*ResourcePages, error) {
+	if err := c.fetchResourcePage(ctx, resource, id, 0); err != nil {
+		return nil, err
+	}
+
+	songs := resource.GetSongs()
+
+	return &ResourcePages{
+		client:   c,
+		resource: resource,
+		id:       id,
+		first:    songs,
+		fetched:  len(songs),
+		done:     len(songs) == 0,
+	}, nil
+}
+
+// Total returns the number of songs of the resource, as announced by the
+// first page.
+func (p *ResourcePages) Total() int {
+	if paged, ok := p.resource.(PagedResource); ok {
+		return paged.GetTotalSongs()
+	}
+
+	return p.fetched
+}
+
+// Next returns the songs of the next page after appending them to the
+// resource, or nil once every page has been returned.
+func (p *ResourcePages) Next(ctx context.Context) ([]*Song, error) {
+	if p.first != nil {
+		songs := p.first
+		p.first = nil
+		p.done = p.done || p.fetched >= p.Total()
+		return songs, nil
+	}
+	if p.done {
+		return nil, nil
+	}
+
+	page := reflect.New(reflect.TypeOf(p.resource).Elem()).Interface().(Resource)
+	if err := p.client.fetchResourcePage(ctx, page, p.id, p.fetched); err != nil {
+		return nil, fmt.Errorf("failed to fetch songs %d to %d: %w", p.fetched+1, p.fetched+pageSize, err)
+	}
+
+	songs := page.GetSongs()
+	p.fetched += len(songs)
+	p.done = len(songs) == 0 || p.fetched >= p.Total()
+	if len(songs) == 0 {
+		return nil, nil
+	}
+
+	p.resource.SetSongs(append(p.resource.GetSongs(), songs...))
+
+	return songs, nil
+}
@@ -3,10 +3,7 @@ package deezer
 import (
 	"encoding/json"
 	"fmt"
-	"path"
 	"time"
-
-	"github.com/flytam/filenamify"
 )
 
 type Playlist struct {
@@ -16,9 +13,11 @@ type Playlist struct {
 			Status   int    `json:"STATUS"`
 			Creator  string `json:"PARENT_USERNAME"`
 			Duration int    `json:"DURATION"`
+			Checksum string `json:"CHECKSUM"`
 		} `json:"DATA"`
 		Songs struct {
-			Data []*Song `json:"data"`
+			Data  []*Song `json:"data"`
+			Total int     `json:"total"`
 		} `json:"SONGS"`
 	} `json:"results"`
 }
@@ -33,7 +32,7 @@ Duration: %s
 =================================================`,
 		p.Results.Data.Title,
 		p.Results.Data.Creator,
-		len(p.Results.Songs.Data),
+		p.GetTotalSongs(),
 		time.Duration(p.Results.Data.Duration)*time.Second,
 	)
 }
@@ -54,11 +53,8 @@ func (p *Playlist) SetSongs(s []*Song) {
 	p.Results.Songs.Data = s
 }
 
-func (p *Playlist) GetOutputDir(outputDir string) string {
-	p.Results.Data.Title, _ = filenamify.Filenamify(p.Results.Data.Title, filenamify.Options{})
-	outputDir = path.Join(outputDir, p.Results.Data.Title)
-
-	return outputDir
+func (p *Playlist) GetTotalSongs() int {
+	return max(p.Results.Songs.Total, len(p.Results.Songs.Data))
 }
 
 func (p *Playlist) Unmarshal(data []byte) error {
@@ -5,6 +5,5 @@ type Resource interface {
 	GetType() string
 	GetSongs() []*Song
 	SetSongs(songs []*Song)
-	GetOutputDir(outputDir string) string
 	Unmarshal(data []byte) error
 }
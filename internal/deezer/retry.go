@@ -0,0 +1,215 @@
+package deezer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRetryAfter caps the delay requested by a Retry-After header.
+const maxRetryAfter = time.Minute
+
+// attemptTimeout limits each attempt of a request, including reading its
+// response body, so that a stalled attempt is retried. The delays between
+// attempts are not part of it.
+const attemptTimeout = 20 * time.Second
+
+// RetryPolicy configures how failed requests are retried.
+type RetryPolicy struct {
+	// Retries is the number of retries after the first attempt
+	Retries int
+	// Backoff is the delay before the first retry, doubled on each retry
+	// up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Jitter is the fraction of the delay that is randomised, from 0 to 1
+	Jitter float64
+	// OnRetry is called with a description of each retry, unless the
+	// request context has a notifier set by WithRetryNotifier
+	OnRetry func(msg string)
+}
+
+// DefaultRetryPolicy is used when no retry policy is given.
+var DefaultRetryPolicy = RetryPolicy{
+	Retries:    3,
+	Backoff:    time.Second,
+	MaxBackoff: 30 * time.Second,
+	Jitter:     0.5,
+}
+
+// delay returns the delay before the given retry, starting at 1.
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.Backoff << (retry - 1)
+	if d <= 0 || (p.MaxBackoff > 0 && d > p.MaxBackoff) {
+		d = p.MaxBackoff
+	}
+
+	jitter := time.Duration(float64(d) * p.Jitter * rand.Float64())
+
+	return d - jitter
+}
+
+type retryNotifierKey struct{}
+
+// WithRetryNotifier returns a context whose requests call notify with a
+// description of each retry, so that callers can report them.
+func WithRetryNotifier(ctx context.Context, notify func(msg string)) context.Context {
+	return context.WithValue(ctx, retryNotifierKey{}, notify)
+}
+
+type streamingKey struct{}
+
+// withStreaming returns a context whose requests are not limited by
+// attemptTimeout, for media streams that take longer to read.
+func withStreaming(ctx context.Context) context.Context {
+	return context.WithValue(ctx, streamingKey{}, true)
+}
+
+// retryTransport retries requests failing with a network error, a 429 or
+// 5xx status, or a gw-light quota error. It replaces the timeout of the
+// HTTP client, which would also cut the retries short.
+type retryTransport struct {
+	base    http.RoundTripper
+	policy  RetryPolicy
+	timeout time.Duration
+}
+
+func newRetryTransport(base http.RoundTripper, policy RetryPolicy) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &retryTransport{base: base, policy: policy, timeout: attemptTimeout}
+}
+
+// cancelBody cancels the context of an attempt once its body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+
+	return err
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for retry := 0; ; retry++ {
+		attempt := req
+		if retry > 0 && req.Body != nil {
+			if req.GetBody == nil {
+				return nil, fmt.Errorf("cannot retry request without GetBody")
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attempt = req.Clone(req.Context())
+			attempt.Body = body
+		}
+
+		cancel := context.CancelFunc(func() {})
+		if streaming, _ := req.Context().Value(streamingKey{}).(bool); !streaming && t.timeout > 0 {
+			var ctx context.Context
+			ctx, cancel = context.WithTimeout(req.Context(), t.timeout)
+			attempt = attempt.WithContext(ctx)
+		}
+
+		resp, err := t.base.RoundTrip(attempt)
+		reason, retryAfter := t.retryReason(resp, err)
+		if reason == "" || retry >= t.policy.Retries || req.Context().Err() != nil {
+			if resp == nil {
+				cancel()
+				return nil, err
+			}
+			resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+			return resp, err
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		cancel()
+
+		delay := t.policy.delay(retry + 1)
+		if retryAfter > 0 {
+			delay = retryAfter
+		}
+
+		notify, ok := req.Context().Value(retryNotifierKey{}).(func(string))
+		if !ok {
+			notify = t.policy.OnRetry
+		}
+		if notify != nil {
+			notify(fmt.Sprintf("%s, retrying %s in %s (%d/%d)",
+				reason, req.URL.Host, delay.Round(100*time.Millisecond), retry+1, t.policy.Retries))
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// retryReason describes why a request should be retried, or returns an
+// empty string when it should not. The response body of gw-light calls is
+// read and replaced so that it can still be read by the caller.
+func (t *retryTransport) retryReason(resp *http.Response, err error) (string, time.Duration) {
+	if err != nil {
+		return fmt.Sprintf("request failed: %v", err), 0
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "rate limited", parseRetryAfter(resp.Header.Get("Retry-After"))
+	case resp.StatusCode >= 500:
+		return fmt.Sprintf("unexpected status code: %d", resp.StatusCode), parseRetryAfter(resp.Header.Get("Retry-After"))
+	case resp.StatusCode == http.StatusOK && resp.Request != nil && strings.HasSuffix(resp.Request.URL.Path, "/gw-light.php"):
+		body, readErr := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+		if readErr == nil && isQuotaError(body) {
+			return "quota exceeded", 0
+		}
+	}
+
+	return "", 0
+}
+
+// isQuotaError reports whether a gw-light response failed because too many
+// requests were made.
+func isQuotaError(body []byte) bool {
+	return strings.Contains(strings.ToLower(gatewayError(body)), "quota")
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as a
+// date, returning 0 when it is missing or invalid.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if date, err := http.ParseTime(value); err == nil {
+		d = time.Until(date)
+	}
+
+	if d <= 0 {
+		return 0
+	}
+
+	return min(d, maxRetryAfter)
+}
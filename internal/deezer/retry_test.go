@@ -0,0 +1,163 @@
+package deezer
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{Backoff: time.Second, MaxBackoff: 30 * time.Second, Jitter: 0.5}
+	tests := []struct {
+		retry int
+		base  time.Duration
+	}{
+		{retry: 1, base: time.Second},
+		{retry: 2, base: 2 * time.Second},
+		{retry: 3, base: 4 * time.Second},
+		{retry: 5, base: 16 * time.Second},
+		{retry: 6, base: 30 * time.Second},
+		{retry: 20, base: 30 * time.Second},
+		// The shift overflows
+		{retry: 70, base: 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		low := tt.base - time.Duration(float64(tt.base)*policy.Jitter)
+		for range 100 {
+			if d := policy.delay(tt.retry); d < low || d > tt.base {
+				t.Fatalf("delay(%d) = %s, want between %s and %s", tt.retry, d, low, tt.base)
+			}
+		}
+	}
+
+	policy.Jitter = 0
+	if d := policy.delay(3); d != 4*time.Second {
+		t.Errorf("delay(3) without jitter = %s, want 4s", d)
+	}
+
+	policy.MaxBackoff = 0
+	if d := policy.delay(10); d != 512*time.Second {
+		t.Errorf("delay(10) without cap = %s, want 8m32s", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		value    string
+		min, max time.Duration
+	}{
+		{value: ""},
+		{value: "0"},
+		{value: "5", min: 5 * time.Second, max: 5 * time.Second},
+		{value: "120", min: maxRetryAfter, max: maxRetryAfter},
+		{value: "-3"},
+		{value: "1.5"},
+		{value: "soon"},
+		{value: "Wed, 21 Oct 2015 07:28:00 GMT"},
+		{value: time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), min: 8 * time.Second, max: 10 * time.Second},
+		{value: time.Now().Add(time.Hour).UTC().Format(http.TimeFormat), min: maxRetryAfter, max: maxRetryAfter},
+	}
+
+	for _, tt := range tests {
+		if d := parseRetryAfter(tt.value); d < tt.min || d > tt.max {
+			t.Errorf("parseRetryAfter(%q) = %s, want between %s and %s", tt.value, d, tt.min, tt.max)
+		}
+	}
+}
+
+// newRetryClient returns a client retrying as a session does, with a short
+// attempt timeout.
+func newRetryClient(policy RetryPolicy, timeout time.Duration) *http.Client {
+	transport := newRetryTransport(http.DefaultTransport, policy)
+	transport.timeout = timeout
+
+	return &http.Client{Transport: transport}
+}
+
+func get(t *testing.T, ctx context.Context, client *http.Client, url string) string {
+	t.Helper()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(body)
+}
+
+func TestRetryTransportStalledAttempt(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	client := newRetryClient(RetryPolicy{Retries: 2, Backoff: 10 * time.Millisecond}, 100*time.Millisecond)
+	if body := get(t, context.Background(), client, srv.URL); body != "ok" {
+		t.Errorf("got body %q, want ok", body)
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("got %d attempts, want 2", n)
+	}
+}
+
+func TestRetryTransportRetryAfterLongerThanAttempt(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	client := newRetryClient(RetryPolicy{Retries: 1, Backoff: 10 * time.Millisecond}, 200*time.Millisecond)
+	start := time.Now()
+	if body := get(t, context.Background(), client, srv.URL); body != "ok" {
+		t.Errorf("got body %q, want ok", body)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("retried after %s, want the 1s of Retry-After", elapsed)
+	}
+}
+
+func TestRetryTransportStreaming(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		io.WriteString(w, "first part, ")
+		w.(http.Flusher).Flush()
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "second part")
+	}))
+	defer srv.Close()
+
+	client := newRetryClient(RetryPolicy{Retries: 1, Backoff: 10 * time.Millisecond}, 50*time.Millisecond)
+	if body := get(t, withStreaming(context.Background()), client, srv.URL); body != "first part, second part" {
+		t.Errorf("got body %q, want the whole stream", body)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("got %d attempts, want 1", n)
+	}
+}
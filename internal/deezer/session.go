@@ -7,15 +7,20 @@ import (
 	"io"
 	"net/http"
 	"net/http/cookiejar"
-	"time"
+	"strconv"
+	"sync"
+
+	"github.com/mathismqn/godeez/internal/config"
 )
 
 type UserDataResponse struct {
 	Results struct {
 		APIToken string `json:"checkForm"`
 		User     struct {
-			Id      int `json:"USER_ID"`
-			Options struct {
+			Id            int         `json:"USER_ID"`
+			Name          string      `json:"BLOG_NAME"`
+			LovedTracksID json.Number `json:"LOVEDTRACKS_ID"`
+			Options       struct {
 				LicenseToken  string `json:"license_token"`
 				MobileOffline bool   `json:"mobile_offline"`
 				WebOffline    bool   `json:"web_offline"`
@@ -25,24 +30,121 @@ type UserDataResponse struct {
 }
 
 type Session struct {
-	ArlCookie    string
-	APIToken     string
-	LicenseToken string
-	HttpClient   *http.Client
-	Premium      bool
+	ArlCookie     string
+	UserID        string
+	UserName      string
+	LovedTracksID string
+	HttpClient    *http.Client
+	// Premium is set when the account can download songs for offline use,
+	// on the web player or on mobile
+	Premium       bool
+	WebOffline    bool
+	MobileOffline bool
+	Endpoints     config.Endpoints
+
+	mu           sync.RWMutex
+	refreshMu    sync.Mutex
+	apiToken     string
+	licenseToken string
+	generation   int
 }
 
+// sessionTokens is a snapshot of the tokens of a session. generation is
+// incremented each time the session is refreshed.
+type sessionTokens struct {
+	apiToken     string
+	licenseToken string
+	generation   int
+}
+
+// SessionOptions configures the requests made by a session.
+type SessionOptions struct {
+	// Retry is applied to every request made with the HTTP client of the
+	// session
+	Retry RetryPolicy
+	// Endpoints default to config.DefaultEndpoints when unset
+	Endpoints config.Endpoints
+}
+
+// Authenticate logs in with the ARL cookie, using the default retry policy
+// and endpoints.
 func Authenticate(ctx context.Context, arlCookie string) (*Session, error) {
+	return AuthenticateWithOptions(ctx, arlCookie, SessionOptions{Retry: DefaultRetryPolicy})
+}
+
+// AuthenticateWithOptions logs in with the ARL cookie.
+func AuthenticateWithOptions(ctx context.Context, arlCookie string, opts SessionOptions) (*Session, error) {
 	jar, err := cookiejar.New(nil)
 	if err != nil {
 		return nil, err
 	}
+	// Requests are timed out by the retry transport, one attempt at a time
 	client := &http.Client{
-		Timeout: 20 * time.Second,
-		Jar:     jar,
+		Jar:       jar,
+		Transport: newRetryTransport(http.DefaultTransport, opts.Retry),
+	}
+	endpoints := opts.Endpoints.WithDefaults()
+
+	res, err := fetchUserData(ctx, client, endpoints.Deezer, arlCookie)
+	if err != nil {
+		return nil, err
+	}
+
+	options := res.Results.User.Options
+
+	return &Session{
+		ArlCookie:     arlCookie,
+		UserID:        strconv.Itoa(res.Results.User.Id),
+		UserName:      res.Results.User.Name,
+		LovedTracksID: res.Results.User.LovedTracksID.String(),
+		HttpClient:    client,
+		Premium:       options.MobileOffline || options.WebOffline,
+		WebOffline:    options.WebOffline,
+		MobileOffline: options.MobileOffline,
+		Endpoints:     endpoints,
+		apiToken:      res.Results.APIToken,
+		licenseToken:  res.Results.User.Options.LicenseToken,
+	}, nil
+}
+
+func (s *Session) tokens() sessionTokens {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return sessionTokens{
+		apiToken:     s.apiToken,
+		licenseToken: s.licenseToken,
+		generation:   s.generation,
 	}
+}
+
+// refresh logs in again with the ARL cookie to get new tokens, unless the
+// session was already refreshed since the expired tokens were read.
+func (s *Session) refresh(ctx context.Context, expired sessionTokens) error {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
 
-	url := "https://www.deezer.com/ajax/gw-light.php?method=deezer.getUserData&input=3&api_version=1.0&api_token="
+	if s.tokens().generation != expired.generation {
+		return nil
+	}
+
+	res, err := fetchUserData(ctx, s.HttpClient, s.Endpoints.Deezer, s.ArlCookie)
+	if err != nil {
+		return fmt.Errorf("failed to refresh session: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.apiToken = res.Results.APIToken
+	s.licenseToken = res.Results.User.Options.LicenseToken
+	s.generation++
+
+	return nil
+}
+
+func fetchUserData(ctx context.Context, client *http.Client, baseURL, arlCookie string) (*UserDataResponse, error) {
+	url := baseURL + "/ajax/gw-light.php?method=deezer.getUserData&input=3&api_version=1.0&api_token="
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
@@ -77,13 +179,5 @@ func Authenticate(ctx context.Context, arlCookie string) (*Session, error) {
 		return nil, fmt.Errorf("invalid arl cookie")
 	}
 
-	isPremium := res.Results.User.Options.MobileOffline || res.Results.User.Options.WebOffline
-
-	return &Session{
-		ArlCookie:    arlCookie,
-		APIToken:     res.Results.APIToken,
-		LicenseToken: res.Results.User.Options.LicenseToken,
-		HttpClient:   client,
-		Premium:      isPremium,
-	}, nil
+	return &res, nil
 }
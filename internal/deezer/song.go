@@ -3,8 +3,6 @@ package deezer
 import (
 	"encoding/json"
 	"fmt"
-
-	"github.com/flytam/filenamify"
 )
 
 type Contributors struct {
@@ -31,11 +29,15 @@ type Song struct {
 	Title        string       `json:"SNG_TITLE"`
 	Version      string       `json:"VERSION"`
 	Cover        string       `json:"ALB_PICTURE"`
+	AlbumID      string       `json:"ALB_ID"`
+	AlbumTitle   string       `json:"ALB_TITLE"`
+	ReleaseDate  string       `json:"PHYSICAL_RELEASE_DATE"`
 	Contributors Contributors `json:"SNG_CONTRIBUTORS"`
 	Duration     string       `json:"DURATION"`
 	Gain         string       `json:"GAIN"`
 	ISRC         string       `json:"ISRC"`
 	TrackNumber  string       `json:"TRACK_NUMBER"`
+	DiskNumber   string       `json:"DISK_NUMBER"`
 	TrackToken   string       `json:"TRACK_TOKEN"`
 }
 
@@ -47,19 +49,3 @@ func (s *Song) GetTitle() string {
 
 	return songTitle
 }
-
-func (s *Song) GetFileName(resourceType, mediaFormat string, song *Song) string {
-	ext := "mp3"
-	if mediaFormat == "FLAC" {
-		ext = "flac"
-	}
-	trackNumber := ""
-	if resourceType == "album" {
-		trackNumber = song.TrackNumber + ". "
-	}
-
-	fileName := fmt.Sprintf("%s%s - %s.%s", trackNumber, s.Artist, s.GetTitle(), ext)
-	fileName, _ = filenamify.Filenamify(fileName, filenamify.Options{MaxLength: 255})
-
-	return fileName
-}
@@ -0,0 +1,140 @@
+package deezertest
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+
+	"github.com/mewkiz/flac"
+	"github.com/mewkiz/flac/frame"
+	"github.com/mewkiz/flac/meta"
+)
+
+// mp3FrameSize is the size of an MPEG-1 Layer III frame at 128 kbps and
+// 44.1 kHz without padding.
+const mp3FrameSize = 417
+
+// mp3FrameDuration is the duration of an MPEG-1 Layer III frame at 44.1 kHz,
+// in seconds.
+const mp3FrameDuration = 1152.0 / 44100
+
+// SilentMP3 returns an MP3 file of about seconds seconds of silence.
+func SilentMP3(seconds int) []byte {
+	frames := int(float64(seconds) / mp3FrameDuration)
+
+	// MPEG-1 Layer III, no CRC, 128 kbps, 44.1 kHz, mono. The zeroed side
+	// information decodes as silence.
+	frame := make([]byte, mp3FrameSize)
+	copy(frame, []byte{0xFF, 0xFB, 0x90, 0xC0})
+
+	return bytes.Repeat(frame, frames)
+}
+
+// EmptyFLAC returns a FLAC file made of a STREAMINFO block describing a
+// 44.1 kHz 16-bit stereo stream, followed by size bytes standing for the
+// audio frames, of which only the sync code is valid. It is enough for
+// tagging, not for decoding.
+func EmptyFLAC(size int) []byte {
+	var b bytes.Buffer
+	b.WriteString("fLaC")
+
+	// Last metadata block flag set, type STREAMINFO, 34 bytes long
+	b.Write([]byte{0x80, 0x00, 0x00, 34})
+
+	info := make([]byte, 34)
+	binary.BigEndian.PutUint16(info[0:], 4096) // min block size
+	binary.BigEndian.PutUint16(info[2:], 4096) // max block size
+	// Sample rate (20 bits), channels - 1 (3 bits), bits per sample - 1
+	// (5 bits) and total samples (36 bits), packed into 8 bytes
+	packed := uint64(44100)<<44 | uint64(1)<<41 | uint64(15)<<36
+	binary.BigEndian.PutUint64(info[10:], packed)
+	b.Write(info)
+
+	frames := make([]byte, max(size, 2))
+	copy(frames, []byte{0xFF, 0xF8})
+	b.Write(frames)
+
+	return b.Bytes()
+}
+
+// ToneFLAC returns a decodable FLAC file of seconds seconds of a sine wave
+// at frequency Hz on both channels of a 44.1 kHz 16-bit stereo stream, with
+// amplitude between 0 and 1 relative to full scale.
+func ToneFLAC(seconds, frequency, amplitude float64) []byte {
+	return ToneFLACFormat(seconds, frequency, amplitude, 2, 16)
+}
+
+// ToneFLACFormat is like ToneFLAC with a stream of 1 or 2 channels and
+// bitsPerSample bits per sample.
+func ToneFLACFormat(seconds, frequency, amplitude float64, channels, bitsPerSample int) []byte {
+	const sampleRate, blockSize = 44100, 4096
+	total := int(seconds * sampleRate)
+	fullScale := float64(int64(1)<<(bitsPerSample-1) - 1)
+
+	var b bytes.Buffer
+	info := &meta.StreamInfo{
+		BlockSizeMin:  blockSize,
+		BlockSizeMax:  blockSize,
+		SampleRate:    sampleRate,
+		NChannels:     uint8(channels),
+		BitsPerSample: uint8(bitsPerSample),
+		NSamples:      uint64(total),
+	}
+	enc, err := flac.NewEncoder(&b, info)
+	if err != nil {
+		panic(err)
+	}
+
+	for start, num := 0, uint64(0); start < total; start, num = start+blockSize, num+1 {
+		n := min(blockSize, total-start)
+		samples := make([]int32, n)
+		for i := range samples {
+			t := float64(start+i) / sampleRate
+			samples[i] = int32(math.Round(amplitude * fullScale * math.Sin(2*math.Pi*frequency*t)))
+		}
+
+		// Channel assignments of independent channels are numbered from
+		// ChannelsMono, one channel per step
+		f := &frame.Frame{
+			Header: frame.Header{
+				HasFixedBlockSize: true,
+				BlockSize:         uint16(n),
+				SampleRate:        sampleRate,
+				Channels:          frame.Channels(channels - 1),
+				BitsPerSample:     uint8(bitsPerSample),
+				Num:               num,
+			},
+		}
+		for range channels {
+			f.Subframes = append(f.Subframes, &frame.Subframe{SubHeader: frame.SubHeader{Pred: frame.PredVerbatim}, Samples: samples, NSamples: n})
+		}
+		if err := enc.WriteFrame(f); err != nil {
+			panic(err)
+		}
+	}
+	if err := enc.Close(); err != nil {
+		panic(err)
+	}
+
+	return b.Bytes()
+}
+
+// coverJPEG returns a small JPEG image served as the cover of every album.
+func coverJPEG() []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for x := 0; x < 8; x++ {
+		for y := 0; y < 8; y++ {
+			img.Set(x, y, color.RGBA{R: 0xA2, G: 0x38, B: 0xFF, A: 0xFF})
+		}
+	}
+
+	var b bytes.Buffer
+	if err := jpeg.Encode(&b, img, nil); err != nil {
+		panic(err)
+	}
+
+	return b.Bytes()
+}
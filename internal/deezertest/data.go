@@ -0,0 +1,134 @@
+package deezertest
+
+import (
+	"crypto/md5"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Song is a song served by the fake Deezer.
+type Song struct {
+	ID          string
+	Title       string
+	Version     string
+	Artist      string
+	AlbumID     string
+	AlbumTitle  string
+	Cover       string
+	TrackNumber int
+	// DiscNumber is the disc of the song in its album, 1 when not set
+	DiscNumber  int
+	Duration    int
+	ISRC        string
+	Gain        string
+	ReleaseDate string
+
+	// Audio holds the decrypted file of each available format, keyed by
+	// Deezer format name (MP3_128, MP3_320, FLAC)
+	Audio map[string][]byte
+
+	// Lyrics and SyncedLyrics are served by song.getLyrics, which reports
+	// no lyrics when both are empty
+	Lyrics       string
+	SyncedLyrics []LyricsLine
+
+	// BPM, Key, Mode and Genres are served by the fake songbpm and last.fm
+	BPM    int
+	Key    string
+	Mode   string
+	Genres []string
+}
+
+// TrackToken returns the track token of the song, accepted by get_url.
+func (s *Song) TrackToken() string {
+	return "token-" + s.ID
+}
+
+func (s *Song) gwData() map[string]any {
+	return map[string]any{
+		"SNG_ID":                s.ID,
+		"SNG_TITLE":             s.Title,
+		"VERSION":               s.Version,
+		"ART_NAME":              s.Artist,
+		"ALB_ID":                s.AlbumID,
+		"ALB_TITLE":             s.AlbumTitle,
+		"ALB_PICTURE":           s.Cover,
+		"DURATION":              strconv.Itoa(s.Duration),
+		"GAIN":                  s.Gain,
+		"ISRC":                  s.ISRC,
+		"TRACK_NUMBER":          strconv.Itoa(s.TrackNumber),
+		"DISK_NUMBER":           strconv.Itoa(max(s.DiscNumber, 1)),
+		"TRACK_TOKEN":           s.TrackToken(),
+		"PHYSICAL_RELEASE_DATE": s.ReleaseDate,
+		"SNG_CONTRIBUTORS": map[string]any{
+			"main_artist": []string{s.Artist},
+		},
+	}
+}
+
+// LyricsLine is a line of synchronised lyrics, sung Time after the start of
+// the song.
+type LyricsLine struct {
+	Time time.Duration
+	Text string
+}
+
+func (s *Song) gwLyrics() map[string]any {
+	sync := make([]map[string]string, 0, len(s.SyncedLyrics)+1)
+	for _, line := range s.SyncedLyrics {
+		ms := line.Time.Milliseconds()
+		sync = append(sync, map[string]string{
+			"lrc_timestamp": fmt.Sprintf("[%02d:%02d.%02d]", ms/60000, ms/1000%60, ms/10%100),
+			"milliseconds":  strconv.FormatInt(ms, 10),
+			"duration":      "0",
+			"line":          line.Text,
+		})
+	}
+	if len(sync) > 0 {
+		// Deezer ends synchronised lyrics with an entry without time
+		sync = append(sync, map[string]string{"line": ""})
+	}
+
+	return map[string]any{
+		"LYRICS_ID":        s.ID,
+		"LYRICS_TEXT":      s.Lyrics,
+		"LYRICS_SYNC_JSON": sync,
+	}
+}
+
+// Album is an album served by the fake Deezer.
+type Album struct {
+	ID          string
+	Title       string
+	Artist      string
+	Label       string
+	ReleaseDate string
+	// Type is the release type index used by the discography: 0 for
+	// singles, 1 for albums, 2 for compilations and 3 for EPs
+	Type    int
+	SongIDs []string
+}
+
+// Playlist is a playlist served by the fake Deezer.
+type Playlist struct {
+	ID      string
+	Title   string
+	Creator string
+	SongIDs []string
+}
+
+// Checksum changes whenever the songs of the playlist change, like the
+// CHECKSUM field of Deezer.
+func (p *Playlist) Checksum() string {
+	return fmt.Sprintf("%x", md5.Sum([]byte(strings.Join(p.SongIDs, ","))))
+}
+
+// Artist is an artist served by the fake Deezer.
+type Artist struct {
+	ID         string
+	Name       string
+	TopSongIDs []string
+	AlbumIDs   []string
+}
@@ -0,0 +1,103 @@
+package deezertest
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// handleBPMSearch lists the songs with a BPM matching the search query, in
+// the markup of songbpm search results.
+func (s *Server) handleBPMSearch(w http.ResponseWriter, r *http.Request) {
+	if !s.serve(w, "songbpm") {
+		return
+	}
+
+	query := strings.ToLower(r.FormValue("query"))
+
+	s.mu.Lock()
+	var results []*Song
+	for _, song := range s.songs {
+		name := strings.ToLower(song.Artist + " " + song.Title)
+		if song.BPM > 0 && strings.Contains(name, query) {
+			results = append(results, song)
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+
+	var b strings.Builder
+	b.WriteString("<html><body>")
+	for _, song := range results {
+		fmt.Fprintf(&b, `<a class="flex flex-col" href="/song/%s">`, song.ID)
+		fmt.Fprintf(&b, `<p>%s</p><p>%s</p>`, html.EscapeString(song.Artist), html.EscapeString(song.Title))
+		fmt.Fprintf(&b, `<div class="flex-1 flex-col items-center"><span class="text-2xl">%d</span></div>`, song.BPM)
+		fmt.Fprintf(&b, `<div class="flex-1 flex-col items-center"><span class="text-2xl">%d:%02d</span></div>`, song.Duration/60, song.Duration%60)
+		b.WriteString("</a>")
+	}
+	b.WriteString("</body></html>")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
+
+// handleBPMSong serves the songbpm page of a song.
+func (s *Server) handleBPMSong(w http.ResponseWriter, r *http.Request) {
+	if !s.serve(w, "songbpm") {
+		return
+	}
+
+	s.mu.Lock()
+	song, ok := s.songs[r.PathValue("id")]
+	s.mu.Unlock()
+	if !ok || song.BPM == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	mode := song.Mode
+	if mode == "" {
+		mode = "major"
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprintf(w, `<html><body><p>%s by %s has a tempo of <span class="font-bold">%d BPM</span>. `+
+		`It is played with a <span class="font-bold">%s</span> key and a  <span class="font-bold">%s</span> mode.</p></body></html>`,
+		html.EscapeString(song.Title), html.EscapeString(song.Artist), song.BPM, song.Key, mode)
+}
+
+// handleTags serves the last.fm tags page of a song.
+func (s *Server) handleTags(w http.ResponseWriter, r *http.Request) {
+	if !s.serve(w, "lastfm") {
+		return
+	}
+
+	artist, title := r.PathValue("artist"), r.PathValue("title")
+
+	s.mu.Lock()
+	var song *Song
+	for _, candidate := range s.songs {
+		if strings.EqualFold(candidate.Artist, artist) && strings.EqualFold(candidate.Title, title) {
+			song = candidate
+			break
+		}
+	}
+	s.mu.Unlock()
+	if song == nil || len(song.Genres) == 0 {
+		http.NotFound(w, r)
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(`<html><body><ol class="big-tags">`)
+	for _, genre := range song.Genres {
+		fmt.Fprintf(&b, `<li class="big-tags-item"><h3 class="big-tags-item-name"><a href="/tag/%s">%s</a></h3></li>`,
+			html.EscapeString(genre), html.EscapeString(genre))
+	}
+	b.WriteString("</ol></body></html>")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(b.String()))
+}
@@ -0,0 +1,678 @@
+// Package deezertest provides a fake Deezer, along with fake songbpm and
+// last.fm pages, to exercise the downloader without network access or a
+// Deezer account.
+//
+// The server speaks just enough of the gw-light API, the media API and the
+// CDN for godeez to fetch resources, authenticate, refresh its tokens and
+// download songs encrypted with BF_CBC_STRIPE:
+//
+//	srv := deezertest.NewServer()
+//	defer srv.Close()
+//	srv.AddSong(&deezertest.Song{ID: "1", Title: "Song", Artist: "Artist",
+//		Audio: map[string][]byte{"MP3_128": deezertest.SilentMP3(5)}})
+//	appConfig := srv.Config(t.TempDir())
+package deezertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/crypto"
+)
+
+// stripeSize is the size of the chunks of a BF_CBC_STRIPE stream, every
+// third of which is encrypted.
+const stripeSize = 2048
+
+// Server is a fake Deezer. Its fields and the songs, albums, playlists and
+// artists added to it must not be modified once requests are made, except
+// through its methods.
+type Server struct {
+	*httptest.Server
+
+	ArlCookie string
+	SecretKey string
+	UserID    int
+	UserName  string
+	Premium   bool
+	// LovedTracksID is the ID of the playlist of the user's favourite
+	// tracks, listed in their library
+	LovedTracksID string
+
+	mu           sync.Mutex
+	apiToken     string
+	licenseToken string
+	generation   int
+	songs        map[string]*Song
+	albums       map[string]*Album
+	playlists    map[string]*Playlist
+	artists      map[string]*Artist
+	library      struct{ albums, playlists []string }
+	failures     map[string][]int
+	calls        map[string]int
+	cover        []byte
+}
+
+// NewServer starts a fake Deezer. It must be closed with Close.
+func NewServer() *Server {
+	s := &Server{
+		ArlCookie:     "arl-cookie",
+		SecretKey:     "0123456789abcdef",
+		UserID:        1000,
+		UserName:      "deezertest",
+		Premium:       true,
+		LovedTracksID: "2000",
+		songs:         make(map[string]*Song),
+		albums:        make(map[string]*Album),
+		playlists:     make(map[string]*Playlist),
+		artists:       make(map[string]*Artist),
+		failures:      make(map[string][]int),
+		calls:         make(map[string]int),
+		cover:         coverJPEG(),
+	}
+	s.rotateTokens()
+	s.playlists[s.LovedTracksID] = &Playlist{ID: s.LovedTracksID, Title: "Loved Tracks"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ajax/gw-light.php", s.handleGateway)
+	mux.HandleFunc("POST /v1/get_url", s.handleGetURL)
+	mux.HandleFunc("GET /media/{format}/{id}", s.handleMedia)
+	mux.HandleFunc("GET /images/cover/", s.handleCover)
+	mux.HandleFunc("POST /searches", s.handleBPMSearch)
+	mux.HandleFunc("GET /song/{id}", s.handleBPMSong)
+	mux.HandleFunc("GET /music/{artist}/{title}/+tags", s.handleTags)
+	s.Server = httptest.NewServer(mux)
+
+	return s
+}
+
+// Endpoints returns endpoints pointing every service at the server.
+func (s *Server) Endpoints() config.Endpoints {
+	return config.Endpoints{
+		Deezer:  s.URL,
+		Media:   s.URL,
+		Images:  s.URL,
+		SongBPM: s.URL,
+		LastFM:  s.URL,
+	}
+}
+
+// Config returns a validated configuration using the server, with homeDir
+// as home directory.
+func (s *Server) Config(homeDir string) *config.Config {
+	appConfig := &config.Config{
+		ArlCookie: s.ArlCookie,
+		SecretKey: s.SecretKey,
+		OutputDir: filepath.Join(homeDir, "Music"),
+		Endpoints: s.Endpoints(),
+		HomeDir:   homeDir,
+	}
+	if err := appConfig.Validate(); err != nil {
+		panic(err)
+	}
+
+	return appConfig
+}
+
+// AddSong adds a song. It is only listed in the albums, playlists and
+// artists referencing its ID.
+func (s *Server) AddSong(song *Song) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.songs[song.ID] = song
+}
+
+// AddAlbum adds an album. Its songs must be added with AddSong.
+func (s *Server) AddAlbum(album *Album) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.albums[album.ID] = album
+}
+
+// AddPlaylist adds a playlist. Its songs must be added with AddSong.
+func (s *Server) AddPlaylist(playlist *Playlist) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.playlists[playlist.ID] = playlist
+}
+
+// AddArtist adds an artist. Its songs and albums must be added with AddSong
+// and AddAlbum.
+func (s *Server) AddArtist(artist *Artist) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.artists[artist.ID] = artist
+}
+
+// SetFavorites replaces the favourite tracks of the user.
+func (s *Server) SetFavorites(songIDs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.playlists[s.LovedTracksID].SongIDs = songIDs
+}
+
+// SetLibrary replaces the albums and playlists saved in the user's library.
+func (s *Server) SetLibrary(albumIDs, playlistIDs []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.library.albums = albumIDs
+	s.library.playlists = playlistIDs
+}
+
+// ExpireTokens invalidates the API and license tokens handed out so far, as
+// Deezer does after a while. New tokens are handed out on the next login.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.rotateTokens()
+}
+
+// Fail makes the next requests to a route answer with the given status
+// codes, one per request. Routes are gw-light methods such as
+// deezer.pageAlbum, or get_url, media, cover, songbpm and lastfm.
+func (s *Server) Fail(route string, statuses ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[route] = append(s.failures[route], statuses...)
+}
+
+// Calls returns the number of requests made to a route, including failed
+// ones. Routes are named as for Fail.
+func (s *Server) Calls(route string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls[route]
+}
+
+func (s *Server) rotateTokens() {
+	s.generation++
+	s.apiToken = fmt.Sprintf("api-token-%d", s.generation)
+	s.licenseToken = fmt.Sprintf("license-token-%d", s.generation)
+}
+
+// serve counts a request to route and answers it with an injected failure
+// if there is one, in which case it returns false.
+func (s *Server) serve(w http.ResponseWriter, route string) bool {
+	s.mu.Lock()
+	s.calls[route]++
+	var status int
+	if failures := s.failures[route]; len(failures) > 0 {
+		status = failures[0]
+		s.failures[route] = failures[1:]
+	}
+	s.mu.Unlock()
+
+	if status == 0 {
+		return true
+	}
+	if status == http.StatusTooManyRequests {
+		w.Header().Set("Retry-After", "0")
+	}
+	http.Error(w, http.StatusText(status), status)
+
+	return false
+}
+
+func (s *Server) handleGateway(w http.ResponseWriter, r *http.Request) {
+	method := r.URL.Query().Get("method")
+	if !s.serve(w, method) {
+		return
+	}
+
+	if method == "deezer.getUserData" {
+		s.handleUserData(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	validToken := r.URL.Query().Get("api_token") == s.apiToken
+	s.mu.Unlock()
+	if !validToken {
+		writeGateway(w, map[string]string{"VALID_TOKEN_REQUIRED": "Invalid CSRF token"}, struct{}{})
+		return
+	}
+
+	var payload gatewayPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch method {
+	case "deezer.pageAlbum":
+		s.pageAlbum(w, payload)
+	case "deezer.pagePlaylist":
+		s.pagePlaylist(w, payload)
+	case "deezer.pageTrack":
+		s.pageTrack(w, payload)
+	case "deezer.pageArtist":
+		s.pageArtist(w, payload)
+	case "album.getDiscography":
+		s.discography(w, payload)
+	case "deezer.pageProfile":
+		s.pageProfile(w, payload)
+	case "song.getLyrics":
+		s.lyrics(w, payload)
+	default:
+		writeGateway(w, map[string]string{"GATEWAY_ERROR": "unknown method " + method}, struct{}{})
+	}
+}
+
+func (s *Server) handleUserData(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie("arl")
+	if err != nil || cookie.Value != s.ArlCookie {
+		writeGateway(w, nil, map[string]any{
+			"checkForm": "",
+			"USER":      map[string]any{"USER_ID": 0},
+		})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeGateway(w, nil, map[string]any{
+		"checkForm": s.apiToken,
+		"USER": map[string]any{
+			"USER_ID":        s.UserID,
+			"BLOG_NAME":      s.UserName,
+			"LOVEDTRACKS_ID": s.LovedTracksID,
+			"OPTIONS": map[string]any{
+				"license_token":  s.licenseToken,
+				"web_offline":    s.Premium,
+				"mobile_offline": s.Premium,
+			},
+		},
+	})
+}
+
+// gatewayPayload holds the parameters of the gw-light methods served.
+type gatewayPayload struct {
+	AlbumID    param `json:"alb_id"`
+	PlaylistID param `json:"playlist_id"`
+	SongID     param `json:"sng_id"`
+	ArtistID   param `json:"art_id"`
+	UserID     param `json:"user_id"`
+	Tab        param `json:"tab"`
+	Start      int   `json:"start"`
+	Count      int   `json:"nb"`
+}
+
+// param is a gw-light parameter, which Deezer accepts as a string or a
+// number.
+type param string
+
+func (p *param) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*p = param(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*p = param(n)
+
+	return nil
+}
+
+func (s *Server) pageAlbum(w http.ResponseWriter, payload gatewayPayload) {
+	album, ok := s.albums[string(payload.AlbumID)]
+	if !ok {
+		writeGateway(w, map[string]string{"DATA_ERROR": "album::getData"}, struct{}{})
+		return
+	}
+
+	duration, discs := 0, 1
+	for _, id := range album.SongIDs {
+		if song, ok := s.songs[id]; ok {
+			duration += song.Duration
+			discs = max(discs, song.DiscNumber)
+		}
+	}
+
+	writeGateway(w, nil, map[string]any{
+		"DATA": map[string]any{
+			"ALB_ID":                album.ID,
+			"NUMBER_TRACK":          strconv.Itoa(len(album.SongIDs)),
+			"NUMBER_DISK":           strconv.Itoa(discs),
+			"ALB_TITLE":             album.Title,
+			"ART_NAME":              album.Artist,
+			"LABEL_NAME":            album.Label,
+			"ORIGINAL_RELEASE_DATE": album.ReleaseDate,
+			"PHYSICAL_RELEASE_DATE": album.ReleaseDate,
+			"DURATION":              strconv.Itoa(duration),
+		},
+		"SONGS": s.songPage(album.SongIDs, payload),
+	})
+}
+
+func (s *Server) pagePlaylist(w http.ResponseWriter, payload gatewayPayload) {
+	playlist, ok := s.playlists[string(payload.PlaylistID)]
+	if !ok {
+		writeGateway(w, map[string]string{"DATA_ERROR": "playlist::getData"}, struct{}{})
+		return
+	}
+
+	duration := 0
+	for _, id := range playlist.SongIDs {
+		if song, ok := s.songs[id]; ok {
+			duration += song.Duration
+		}
+	}
+
+	writeGateway(w, nil, map[string]any{
+		"DATA": map[string]any{
+			"PLAYLIST_ID":     playlist.ID,
+			"TITLE":           playlist.Title,
+			"STATUS":          0,
+			"PARENT_USERNAME": playlist.Creator,
+			"DURATION":        duration,
+			"CHECKSUM":        playlist.Checksum(),
+		},
+		"SONGS": s.songPage(playlist.SongIDs, payload),
+	})
+}
+
+func (s *Server) pageTrack(w http.ResponseWriter, payload gatewayPayload) {
+	song, ok := s.songs[string(payload.SongID)]
+	if !ok {
+		writeGateway(w, map[string]string{"DATA_ERROR": "song::getData"}, struct{}{})
+		return
+	}
+
+	writeGateway(w, nil, map[string]any{"DATA": song.gwData()})
+}
+
+func (s *Server) lyrics(w http.ResponseWriter, payload gatewayPayload) {
+	song, ok := s.songs[string(payload.SongID)]
+	if !ok || (song.Lyrics == "" && len(song.SyncedLyrics) == 0) {
+		writeGateway(w, map[string]string{"DATA_ERROR": "lyrics::getLyrics"}, struct{}{})
+		return
+	}
+
+	writeGateway(w, nil, song.gwLyrics())
+}
+
+func (s *Server) pageArtist(w http.ResponseWriter, payload gatewayPayload) {
+	artist, ok := s.artists[string(payload.ArtistID)]
+	if !ok {
+		writeGateway(w, map[string]string{"DATA_ERROR": "artist::getData"}, struct{}{})
+		return
+	}
+
+	writeGateway(w, nil, map[string]any{
+		"DATA": map[string]any{
+			"ART_ID":   artist.ID,
+			"ART_NAME": artist.Name,
+		},
+		"TOP": s.songPage(artist.TopSongIDs, payload),
+	})
+}
+
+func (s *Server) discography(w http.ResponseWriter, payload gatewayPayload) {
+	artist, ok := s.artists[string(payload.ArtistID)]
+	if !ok {
+		writeGateway(w, map[string]string{"DATA_ERROR": "artist::getData"}, struct{}{})
+		return
+	}
+
+	start, end := pageBounds(len(artist.AlbumIDs), payload)
+	releases := make([]map[string]any, 0, end-start)
+	for _, id := range artist.AlbumIDs[start:end] {
+		album, ok := s.albums[id]
+		if !ok {
+			continue
+		}
+		releases = append(releases, map[string]any{
+			"ALB_ID":                album.ID,
+			"ALB_TITLE":             album.Title,
+			"ART_NAME":              album.Artist,
+			"TYPE":                  strconv.Itoa(album.Type),
+			"ROLE_ID":               "0",
+			"ORIGINAL_RELEASE_DATE": album.ReleaseDate,
+			"PHYSICAL_RELEASE_DATE": album.ReleaseDate,
+		})
+	}
+
+	writeGateway(w, nil, map[string]any{
+		"data":  releases,
+		"total": len(artist.AlbumIDs),
+	})
+}
+
+func (s *Server) pageProfile(w http.ResponseWriter, payload gatewayPayload) {
+	if string(payload.UserID) != strconv.Itoa(s.UserID) {
+		writeGateway(w, map[string]string{"DATA_ERROR": "user::getData"}, struct{}{})
+		return
+	}
+
+	var tab map[string]any
+	switch payload.Tab {
+	case "albums":
+		start, end := pageBounds(len(s.library.albums), payload)
+		albums := make([]map[string]any, 0, end-start)
+		for _, id := range s.library.albums[start:end] {
+			albums = append(albums, map[string]any{"ALB_ID": id})
+		}
+		tab = map[string]any{"albums": map[string]any{"data": albums, "total": len(s.library.albums)}}
+	case "playlists":
+		// Deezer lists the favourite tracks first
+		ids := append([]string{s.LovedTracksID}, s.library.playlists...)
+		start, end := pageBounds(len(ids), payload)
+		playlists := make([]map[string]any, 0, end-start)
+		for _, id := range ids[start:end] {
+			playlists = append(playlists, map[string]any{"PLAYLIST_ID": id})
+		}
+		tab = map[string]any{"playlists": map[string]any{"data": playlists, "total": len(ids)}}
+	default:
+		writeGateway(w, map[string]string{"PARAMETER_ERROR": "invalid tab"}, struct{}{})
+		return
+	}
+
+	writeGateway(w, nil, map[string]any{"TAB": tab})
+}
+
+// songPage returns the songs of a page as returned in the SONGS field of
+// gw-light responses. Unknown songs are left out, as Deezer does for songs
+// that are no longer available.
+func (s *Server) songPage(ids []string, payload gatewayPayload) map[string]any {
+	start, end := pageBounds(len(ids), payload)
+	songs := make([]map[string]any, 0, end-start)
+	for _, id := range ids[start:end] {
+		if song, ok := s.songs[id]; ok {
+			songs = append(songs, song.gwData())
+		}
+	}
+
+	return map[string]any{
+		"data":  songs,
+		"count": len(songs),
+		"total": len(ids),
+	}
+}
+
+func pageBounds(total int, payload gatewayPayload) (int, int) {
+	start := min(max(payload.Start, 0), total)
+	end := total
+	if payload.Count > 0 {
+		end = min(start+payload.Count, total)
+	}
+
+	return start, end
+}
+
+func writeGateway(w http.ResponseWriter, gatewayError, results any) {
+	if gatewayError == nil {
+		gatewayError = []any{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"error":   gatewayError,
+		"results": results,
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) handleGetURL(w http.ResponseWriter, r *http.Request) {
+	if !s.serve(w, "get_url") {
+		return
+	}
+
+	var req struct {
+		LicenseToken string `json:"license_token"`
+		Media        []struct {
+			Formats []struct {
+				Format string `json:"format"`
+			} `json:"formats"`
+		} `json:"media"`
+		TrackTokens []string `json:"track_tokens"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if req.LicenseToken != s.licenseToken {
+		writeJSON(w, http.StatusBadRequest, map[string]any{
+			"errors": []map[string]any{{"code": 1000, "message": "Invalid license token"}},
+		})
+		return
+	}
+
+	var formats []string
+	for _, media := range req.Media {
+		for _, format := range media.Formats {
+			formats = append(formats, format.Format)
+		}
+	}
+
+	data := make([]map[string]any, 0, len(req.TrackTokens))
+	for _, token := range req.TrackTokens {
+		data = append(data, s.trackMedia(token, formats))
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"data": data})
+}
+
+// trackMedia returns the media of a track in the first available format.
+func (s *Server) trackMedia(token string, formats []string) map[string]any {
+	var song *Song
+	for _, candidate := range s.songs {
+		if candidate.TrackToken() == token {
+			song = candidate
+			break
+		}
+	}
+	if song == nil {
+		return map[string]any{
+			"errors": []map[string]any{{"code": 2002, "message": "Invalid track token"}},
+		}
+	}
+
+	for _, format := range formats {
+		if _, ok := song.Audio[format]; !ok {
+			continue
+		}
+
+		return map[string]any{
+			"media": []map[string]any{{
+				"media_type": "FULL",
+				"cipher":     map[string]string{"type": "BF_CBC_STRIPE"},
+				"format":     format,
+				"sources": []map[string]string{{
+					"url":      fmt.Sprintf("%s/media/%s/%s", s.URL, format, song.ID),
+					"provider": "ak",
+				}},
+			}},
+		}
+	}
+
+	return map[string]any{
+		"errors": []map[string]any{{"code": 2001, "message": "Track token has no sufficient rights on requested media"}},
+	}
+}
+
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	if !s.serve(w, "media") {
+		return
+	}
+
+	s.mu.Lock()
+	song, ok := s.songs[r.PathValue("id")]
+	var audio []byte
+	if ok {
+		audio, ok = song.Audio[r.PathValue("format")]
+	}
+	s.mu.Unlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	stream, err := encryptStripes(audio, crypto.GetKey(s.SecretKey, song.ID))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(stream))
+}
+
+// encryptStripes encrypts every third full chunk of audio, starting with
+// the first one, as BF_CBC_STRIPE streams are.
+func encryptStripes(audio, key []byte) ([]byte, error) {
+	stream := make([]byte, 0, len(audio))
+	for i := 0; i < len(audio); i += stripeSize {
+		chunk := audio[i:min(i+stripeSize, len(audio))]
+		if (i/stripeSize)%3 == 0 && len(chunk) == stripeSize {
+			encrypted, err := crypto.Encrypt(chunk, key)
+			if err != nil {
+				return nil, err
+			}
+			chunk = encrypted
+		}
+		stream = append(stream, chunk...)
+	}
+
+	return stream, nil
+}
+
+func (s *Server) handleCover(w http.ResponseWriter, r *http.Request) {
+	if !s.serve(w, "cover") {
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Write(s.cover)
+}
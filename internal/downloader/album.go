@@ -0,0 +1,72 @@
+package downloader
+
+import (
+	"context"
+	"sync"
+
+	"github.com/mathismqn/godeez/internal/deezer"
+)
+
+// cachedAlbum is an album fetched once for all the songs it contains. The
+// mutex makes the songs of an album wait for a fetch in progress rather
+// than fetch the album again.
+type cachedAlbum struct {
+	mu    sync.Mutex
+	album *deezer.Album
+}
+
+// songAlbum returns the album a song belongs to, used to tag songs of
+// playlists, artists and single tracks like those of an album. Albums are
+// fetched once per client, however many of their songs are downloaded.
+// A failed fetch is not cached, so the next song of the album fetches it
+// again with its own context. It returns nil when the song has no album.
+func (c *Client) songAlbum(ctx context.Context, resource deezer.Resource, song *deezer.Song) (*deezer.Album, error) {
+	if album, ok := resource.(*deezer.Album); ok {
+		return album, nil
+	}
+	if song.AlbumID == "" || song.AlbumID == "0" {
+		return nil, nil
+	}
+
+	c.albumsMu.Lock()
+	entry, ok := c.albums[song.AlbumID]
+	if !ok {
+		entry = &cachedAlbum{}
+		c.albums[song.AlbumID] = entry
+	}
+	c.albumsMu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.album != nil {
+		return entry.album, nil
+	}
+	album, err := c.deezerClient.FetchAlbum(ctx, song.AlbumID)
+	if err != nil {
+		return nil, err
+	}
+	entry.album = album
+
+	return album, nil
+}
+
+// fetchedAlbum is like songAlbum without fetching the album, returning nil
+// when it has not been fetched.
+func (c *Client) fetchedAlbum(resource deezer.Resource, song *deezer.Song) *deezer.Album {
+	if album, ok := resource.(*deezer.Album); ok {
+		return album
+	}
+
+	c.albumsMu.Lock()
+	entry, ok := c.albums[song.AlbumID]
+	c.albumsMu.Unlock()
+	if !ok {
+		return nil
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	return entry.album
+}
@@ -0,0 +1,50 @@
+package downloader
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/deezertest"
+)
+
+func TestSongAlbumRetriesFailedFetch(t *testing.T) {
+	srv := deezertest.NewServer()
+	defer srv.Close()
+	srv.AddSong(&deezertest.Song{ID: "1", Title: "One More Time", Artist: "Daft Punk", AlbumID: "10", AlbumTitle: "Discovery"})
+	srv.AddAlbum(&deezertest.Album{ID: "10", Title: "Discovery", Artist: "Daft Punk", SongIDs: []string{"1"}})
+
+	c := New(srv.Config(t.TempDir()), "playlist")
+	if err := c.initDeezerClient(context.Background(), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	song := &deezer.Song{ID: "1", AlbumID: "10"}
+
+	// A song whose download is cancelled does not fail the others
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.songAlbum(canceled, nil, song); err == nil {
+		t.Fatal("songAlbum with a canceled context succeeded")
+	}
+
+	srv.Fail("deezer.pageAlbum", http.StatusInternalServerError)
+	if _, err := c.songAlbum(context.Background(), nil, song); err == nil {
+		t.Fatal("songAlbum succeeded despite the failed fetch")
+	}
+
+	for range 2 {
+		album, err := c.songAlbum(context.Background(), nil, song)
+		if err != nil {
+			t.Fatalf("songAlbum after a failed fetch: %v", err)
+		}
+		if album == nil || album.GetTitle() != "Discovery" {
+			t.Fatalf("songAlbum = %+v, want Discovery", album)
+		}
+	}
+
+	// The failed fetch and the successful one, then the cache
+	if calls := srv.Calls("deezer.pageAlbum"); calls != 2 {
+		t.Errorf("album fetched %d times, want 2", calls)
+	}
+}
@@ -0,0 +1,141 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/logger"
+)
+
+// Batch downloads several resources in a single run. Songs that appear in
+// more than one resource are only downloaded once, and a single summary is
+// printed at the end.
+type Batch struct {
+	appConfig *config.Config
+	Logger    *logger.Logger
+}
+
+func NewBatch(appConfig *config.Config) *Batch {
+	return &Batch{
+		appConfig: appConfig,
+		Logger:    logger.New(nil),
+	}
+}
+
+// Run downloads the resources of links in order. name identifies the batch
+// in the summary, e.g. the file the links were read from.
+func (b *Batch) Run(ctx context.Context, opts Options, name string, links []deezer.Link) error {
+	if len(links) == 0 {
+		return fmt.Errorf("no links to download")
+	}
+
+	first := b.newClient(links[0].Type, nil)
+	if err := first.initDeezerClient(ctx, opts); err != nil {
+		return err
+	}
+
+	return b.run(ctx, opts, first.deezerClient, "batch", "Batch", name, links)
+}
+
+// run downloads the resources of links with an authenticated client and
+// prints a summary of the given type, title and ID.
+func (b *Batch) run(ctx context.Context, opts Options, deezerClient *deezer.Client, summaryType, title, id string, links []deezer.Link) error {
+	startTime := time.Now()
+	summary := newProgressTracker(b.Logger, 0, summaryType, opts.Output)
+	seen := make(map[string]bool)
+	var dirs []string
+	failed := 0
+
+	for _, link := range links {
+		c := b.newClient(link.Type, deezerClient)
+
+		dir, err := c.downloadBatchResource(ctx, opts, link, seen, summary)
+		if errors.Is(err, context.Canceled) {
+			return err
+		}
+		if err != nil {
+			failed++
+			summary.printWarning("failed to download %s: %v", link, err)
+			continue
+		}
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	summary.printSummary(title, id, commonDir(dirs), time.Since(startTime))
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d resources could not be downloaded", failed, len(links))
+	}
+
+	return nil
+}
+
+func (b *Batch) newClient(resourceType string, deezerClient *deezer.Client) *Client {
+	c := New(b.appConfig, resourceType)
+	c.Logger = b.Logger
+	c.deezerClient = deezerClient
+
+	return c
+}
+
+// downloadBatchResource downloads the songs of link that are not in seen,
+// adds those it downloaded or found on disk to seen and adds its results to
+// the stats of summary. Songs that failed are tried again by the following
+// resources that have them.
+func (c *Client) downloadBatchResource(ctx context.Context, opts Options, link deezer.Link, seen map[string]bool, summary *progressTracker) (string, error) {
+	resource, err := c.fetchResource(ctx, link.ID, opts)
+	if err != nil {
+		return "", err
+	}
+
+	songs := resource.GetSongs()
+	if len(songs) == 0 {
+		return "", fmt.Errorf("%s has no songs", c.resourceType)
+	}
+
+	var unique []*deezer.Song
+	queued := make(map[string]bool)
+	for _, song := range songs {
+		if !seen[song.ID] && !queued[song.ID] {
+			queued[song.ID] = true
+			unique = append(unique, song)
+		}
+	}
+	if len(unique) == 0 {
+		c.Logger.Infof("All songs of %s were already downloaded in this batch\n", link)
+		c.writePlaylistFile(opts, resource, summary)
+		return "", nil
+	}
+
+	tmpl, err := c.pathTemplate(opts, resource)
+	if err != nil {
+		return "", err
+	}
+
+	progress := newProgressTracker(c.Logger, len(unique), c.resourceType, opts.Output)
+	resource.SetSongs(unique)
+	progress.printStart(resource, link.ID)
+
+	dir, err := c.downloadAllSongs(ctx, resource, link.ID, opts, tmpl, progress, songsOf(unique))
+	summary.stats.add(progress.stats)
+	for id := range progress.done {
+		seen[id] = true
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// The playlist file lists every song, including those downloaded
+	// earlier in the batch
+	resource.SetSongs(songs)
+	c.applyAlbumReplayGain(ctx, opts, resource, progress)
+	c.writePlaylistFile(opts, resource, progress)
+
+	return dir, nil
+}
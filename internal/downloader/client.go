@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"path"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
@@ -19,6 +19,7 @@ import (
 	"github.com/mathismqn/godeez/internal/provider"
 	"github.com/mathismqn/godeez/internal/store"
 	"github.com/mathismqn/godeez/internal/tags"
+	"github.com/mathismqn/godeez/internal/template"
 )
 
 const chunkSize = 2048
@@ -28,10 +29,24 @@ type Client struct {
 	resourceType string
 	deezerClient *deezer.Client
 	Logger       *logger.Logger
+	// SkipPlaylistFile leaves the M3U8 file to the caller, for downloads of
+	// part of a playlist whose file must list all its songs
+	SkipPlaylistFile bool
 
 	hashIndexOnce sync.Once
 	hashIndex     *fileutil.HashIndex
 	hashIndexErr  error
+
+	pathsMu sync.Mutex
+	paths   map[string]string
+
+	albumsMu sync.Mutex
+	albums   map[string]*cachedAlbum
+
+	// progress reports the songs being downloaded, nil outside of
+	// downloadAllSongs
+	progressMu sync.Mutex
+	progress   *progressTracker
 }
 
 func New(appConfig *config.Config, resourceType string) *Client {
@@ -40,6 +55,8 @@ func New(appConfig *config.Config, resourceType string) *Client {
 		resourceType: resourceType,
 		deezerClient: nil,
 		Logger:       logger.New(nil), // Initialize with a nil logger, can be set later
+		paths:        make(map[string]string),
+		albums:       make(map[string]*cachedAlbum),
 	}
 }
 
@@ -48,17 +65,85 @@ func (c *Client) Run(ctx context.Context, opts Options, id string) error {
 		return err
 	}
 
-	resource, outputDir, err := c.prepareResource(ctx, id, opts)
+	if c.resourceType == "favorites" {
+		id = c.deezerClient.Session.UserID
+	}
+
+	resource, err := deezer.NewResource(c.resourceType)
 	if err != nil {
 		return err
 	}
+	if _, paged := resource.(deezer.PagedResource); !paged {
+		resource, err := c.fetchResource(ctx, id, opts)
+		if err != nil {
+			return err
+		}
+
+		return c.Download(ctx, opts, resource, id)
+	}
+
+	// Songs of paged resources are downloaded as their pages arrive
+	pages, err := c.deezerClient.FetchResourcePages(ctx, resource, id)
+	if err != nil {
+		return fmt.Errorf("failed to fetch resource: %w", err)
+	}
+	if pages.Total() == 0 {
+		return fmt.Errorf("%s has no songs", c.resourceType)
+	}
+
+	return c.download(ctx, opts, resource, id, pages.Total(), pages.Next)
+}
+
+// Fetch authenticates with Deezer and fetches the resource and its songs.
+func (c *Client) Fetch(ctx context.Context, opts Options, id string) (deezer.Resource, error) {
+	if err := c.initDeezerClient(ctx, opts); err != nil {
+		return nil, err
+	}
 
-	return c.downloadAllSongs(ctx, resource, id, opts, outputDir)
+	return c.fetchResource(ctx, id, opts)
+}
+
+// Download downloads the songs of a resource returned by Fetch.
+func (c *Client) Download(ctx context.Context, opts Options, resource deezer.Resource, id string) error {
+	songs := resource.GetSongs()
+	if len(songs) == 0 {
+		return fmt.Errorf("%s has no songs", c.resourceType)
+	}
+
+	return c.download(ctx, opts, resource, id, len(songs), songsOf(songs))
+}
+
+// download downloads total songs of a resource, read from next.
+func (c *Client) download(ctx context.Context, opts Options, resource deezer.Resource, id string, total int, next songSource) error {
+	tmpl, err := c.pathTemplate(opts, resource)
+	if err != nil {
+		return err
+	}
+
+	startTime := time.Now()
+	progress := newProgressTracker(c.Logger, total, c.resourceType, opts.Output)
+	progress.printStart(resource, id)
+
+	outputDir, err := c.downloadAllSongs(ctx, resource, id, opts, tmpl, progress, next)
+	if err != nil {
+		return err
+	}
+
+	c.applyAlbumReplayGain(ctx, opts, resource, progress)
+	c.writePlaylistFile(opts, resource, progress)
+	progress.printSummary(resource.GetTitle(), id, outputDir, time.Since(startTime))
+
+	return nil
 }
 
 func (c *Client) initDeezerClient(ctx context.Context, opts Options) error {
 	var err error
-	c.deezerClient, err = deezer.NewClient(ctx, c.appConfig)
+	policy := opts.retryPolicy()
+	policy.OnRetry = func(msg string) {
+		c.printWarning(opts.Output, msg)
+	}
+
+	c.deezerClient, err = deezer.NewClient(ctx, c.appConfig, policy)
 	if err != nil {
 		return err
 	}
@@ -70,22 +155,41 @@ func (c *Client) initDeezerClient(ctx context.Context, opts Options) error {
 	return nil
 }
 
-func (c *Client) prepareResource(ctx context.Context, id string, opts Options) (deezer.Resource, string, error) {
-	resource, err := c.createResource()
+// printWarning reports a warning that is not tied to a song, such as a
+// retried request, along with the progress of the download in progress or
+// on its own before and after downloads.
+func (c *Client) printWarning(output, msg string) {
+	c.progressMu.Lock()
+	progress := c.progress
+	c.progressMu.Unlock()
+
+	if progress == nil {
+		progress = newProgressTracker(c.Logger, 0, c.resourceType, output)
+	}
+	progress.printWarning("%s", msg)
+}
+
+// setProgress sets the progress warnings are reported with.
+func (c *Client) setProgress(progress *progressTracker) {
+	c.progressMu.Lock()
+	defer c.progressMu.Unlock()
+
+	c.progress = progress
+}
+
+func (c *Client) fetchResource(ctx context.Context, id string, opts Options) (deezer.Resource, error) {
+	resource, err := deezer.NewResource(c.resourceType)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
 
 	if err := c.deezerClient.FetchResource(ctx, resource, id); err != nil {
-		return nil, "", fmt.Errorf("failed to fetch resource: %w", err)
+		return nil, fmt.Errorf("failed to fetch resource: %w", err)
 	}
 
 	songs := resource.GetSongs()
-	if len(songs) == 0 {
-		if c.resourceType == "track" {
-			return nil, "", fmt.Errorf("track with ID %s not found", id)
-		}
-		return nil, "", fmt.Errorf("%s has no songs", c.resourceType)
+	if len(songs) == 0 && c.resourceType == "track" {
+		return nil, fmt.Errorf("track with ID %s not found", id)
 	}
 
 	if c.resourceType == "artist" && len(songs) > opts.Limit {
@@ -93,63 +197,130 @@ func (c *Client) prepareResource(ctx context.Context, id string, opts Options) (
 		resource.SetSongs(songs)
 	}
 
-	resourceOutputDir := resource.GetOutputDir(c.appConfig.OutputDir)
-	if err := fileutil.EnsureDir(resourceOutputDir); err != nil {
-		return nil, "", fmt.Errorf("failed to create output directory: %w", err)
-	}
-
-	return resource, resourceOutputDir, nil
+	return resource, nil
 }
 
-func (c *Client) createResource() (deezer.Resource, error) {
-	switch c.resourceType {
-	case "album":
-		return &deezer.Album{}, nil
-	case "playlist":
-		return &deezer.Playlist{}, nil
-	case "artist":
-		return &deezer.Artist{}, nil
-	case "track":
-		return &deezer.Track{}, nil
-	default:
-		return nil, fmt.Errorf("unsupported resource type: %s", c.resourceType)
+// songSource returns the next songs to download, or nil once every song
+// has been returned.
+type songSource func(ctx context.Context) ([]*deezer.Song, error)
+
+// songsOf returns a songSource returning songs at once.
+func songsOf(songs []*deezer.Song) songSource {
+	return func(context.Context) ([]*deezer.Song, error) {
+		next := songs
+		songs = nil
+		return next, nil
 	}
 }
 
-func (c *Client) downloadAllSongs(ctx context.Context, resource deezer.Resource, resourceID string, opts Options, outputDir string) error {
-	songs := resource.GetSongs()
-	startTime := time.Now()
+type songJob struct {
+	index int
+	song  *deezer.Song
+}
 
-	if c.resourceType != "track" {
-		fmt.Printf("%s\n\nStarting download...\n\n", resource)
-	}
+// downloadAllSongs downloads the songs read from next and returns the
+// directory they were saved to. Songs are downloaded while next is still
+// fetching the following ones.
+func (c *Client) downloadAllSongs(ctx context.Context, resource deezer.Resource, resourceID string, opts Options, tmpl *template.Template, progress *progressTracker, next songSource) (string, error) {
+	poolCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c.setProgress(progress)
+	defer c.setProgress(nil)
+
+	workers := max(opts.Concurrency, 1)
+	jobs := make(chan songJob)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				progress.startDownload(job.index, job.song)
+				songPath := c.songPath(poolCtx, opts, resource, tmpl, job.song)
+				result := c.downloadSong(poolCtx, resource, resourceID, job.song, opts, songPath)
+
+				if result.err != nil && errors.Is(result.err, context.Canceled) {
+					progress.cancelDownload(job.index)
+					cancel()
+					continue
+				}
 
-	progress := newProgressTracker(c.Logger, len(songs), c.resourceType)
+				progress.handleResult(job.index, job.song, result)
+			}
+		}()
+	}
 
-	for i, song := range songs {
-		if ctx.Err() != nil {
-			return ctx.Err()
+	var fetchErr error
+	index := 0
+feed:
+	for {
+		songs, err := next(poolCtx)
+		if err != nil {
+			fetchErr = err
+			break
+		}
+		if songs == nil {
+			break
 		}
 
-		sp := progress.startDownload(i, song)
-		result := c.downloadSong(ctx, resource, song, opts, outputDir)
-		sp.Stop()
-
-		if result.err != nil {
-			if errors.Is(result.err, context.Canceled) {
-				return result.err
+		for _, song := range songs {
+			select {
+			case jobs <- songJob{index: index, song: song}:
+				index++
+			case <-poolCtx.Done():
+				break feed
 			}
 		}
+	}
+	close(jobs)
+	wg.Wait()
+	progress.stop()
 
-		progress.handleResult(i, song, result)
+	if ctx.Err() != nil {
+		return "", ctx.Err()
+	}
+	if fetchErr != nil {
+		progress.printWarning("failed to fetch all songs: %v", fetchErr)
 	}
 
-	progress.printSummary(resource.GetTitle(), resourceID, outputDir, time.Since(startTime))
+	return commonDir(c.songPaths(opts, resource, tmpl)), nil
+}
 
-	return nil
+// writePlaylistFile writes the M3U8 file of albums and playlists, reporting
+// failures as warnings.
+func (c *Client) writePlaylistFile(opts Options, resource deezer.Resource, progress *progressTracker) {
+	if c.SkipPlaylistFile || c.resourceType == "artist" || c.resourceType == "track" {
+		return
+	}
+
+	if _, err := c.WritePlaylistFile(opts, resource); err != nil {
+		progress.printWarning("failed to write playlist file: %v", err)
+	}
 }
 
-func (c *Client) downloadSong(ctx context.Context, resource deezer.Resource, song *deezer.Song, opts Options, outputDir string) downloadResult {
+// downloadSong downloads a song to songPath, which is the rendered template
+// without the file extension. Retried requests are reported as warnings.
+func (c *Client) downloadSong(ctx context.Context, resource deezer.Resource, resourceID string, song *deezer.Song, opts Options, songPath string) downloadResult {
+	var mu sync.Mutex
+	var retries []string
+	ctx = deezer.WithRetryNotifier(ctx, func(msg string) {
+		c.Logger.Warnf("Warning: %s - %s: %s\n", song.Artist, song.GetTitle(), msg)
+		mu.Lock()
+		defer mu.Unlock()
+		retries = append(retries, msg)
+	})
+
+	result := c.fetchAndSaveSong(ctx, resource, resourceID, song, opts, songPath)
+
+	mu.Lock()
+	defer mu.Unlock()
+	result.warnings = append(retries, result.warnings...)
+
+	return result
+}
+
+func (c *Client) fetchAndSaveSong(ctx context.Context, resource deezer.Resource, resourceID string, song *deezer.Song, opts Options, songPath string) downloadResult {
 	var warnings []string
 
 	media, err := c.deezerClient.FetchMedia(ctx, song, opts.Quality)
@@ -162,15 +333,27 @@ func (c *Client) downloadSong(ctx context.Context, resource deezer.Resource, son
 		return handleError(fmt.Errorf("requested quality '%s' not available", opts.Quality))
 	}
 
-	if path, skip := c.shouldSkipDownload(ctx, song.ID, mediaFormat); skip {
-		return handleError(SkipError{Path: path})
+	if path, skip := c.shouldSkipDownload(ctx, song.ID, mediaFormat, c.outputDir(opts)); skip {
+		result := handleError(SkipError{Path: path})
+		result.quality = strings.ToLower(mediaFormat)
+		// The file belongs to this resource too, so that removing the song
+		// from another one keeps it
+		if err := store.AddDownloadSource(c.appConfig.Profile, song.ID, store.Source(c.resourceType, resourceID)); err != nil {
+			result.warnings = append(result.warnings, fmt.Sprintf("failed to save download info: %v", err))
+		}
+		return result
 	}
 
-	metadataFetcher := newMetadataFetcher(c.deezerClient.Session.HttpClient)
+	metadataFetcher := newMetadataFetcher(c.deezerClient.Session.HttpClient, c.deezerClient.Session.Endpoints)
 	metadataResult := metadataFetcher.fetch(ctx, song, opts)
 	warnings = append(warnings, metadataResult.warnings...)
 
-	stream, err := c.deezerClient.GetMediaStream(ctx, media, song.ID)
+	outputPath := c.reservePath(song.ID, songPath+fileExtension(mediaFormat))
+	if err := fileutil.EnsureDir(filepath.Dir(outputPath)); err != nil {
+		return handleError(fmt.Errorf("failed to create output directory: %w", err))
+	}
+
+	stream, partial, err := c.openStream(ctx, media, song.ID, outputPath)
 	if err != nil {
 		return handleError(fmt.Errorf("failed to get media stream: %w", err))
 	}
@@ -178,12 +361,8 @@ func (c *Client) downloadSong(ctx context.Context, resource deezer.Resource, son
 	dlCtx, cancel := context.WithTimeout(ctx, opts.Timeout)
 	defer cancel()
 
-	fileName := song.GetFileName(c.resourceType, mediaFormat, song)
-	outputPath := path.Join(outputDir, fileName)
-
 	key := crypto.GetKey(c.appConfig.SecretKey, song.ID)
-	if err := c.streamToFile(dlCtx, stream, outputPath, key); err != nil {
-		fileutil.DeleteFile(outputPath)
+	if err := c.streamToFile(dlCtx, stream, outputPath, key, partial); err != nil {
 		return handleError(fmt.Errorf("failed to stream to file: %w", err))
 	}
 
@@ -196,26 +375,90 @@ func (c *Client) downloadSong(ctx context.Context, resource deezer.Resource, son
 		warnings = append(warnings, fmt.Sprintf("failed to fetch cover image: %v", err))
 	}
 
-	finalizeWarnings := c.finalizeDownload(resource, song, outputPath, mediaFormat, metadataResult.genre, cover, metadataResult.bpmKey)
+	album, err := c.songAlbum(ctx, resource, song)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		warnings = append(warnings, fmt.Sprintf("failed to fetch album: %v", err))
+	}
+
+	var lyrics *deezer.Lyrics
+	var lyricsStatus string
+	if opts.Lyrics {
+		lyrics, lyricsStatus, err = c.fetchLyrics(ctx, song)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			warnings = append(warnings, fmt.Sprintf("failed to fetch lyrics: %v", err))
+		}
+	}
+
+	// Songs of albums are analysed together once the album is downloaded
+	_, isAlbum := resource.(*deezer.Album)
+	trackGain := opts.ReplayGain && !isAlbum
+
+	finalizeWarnings := c.finalizeDownload(album, resourceID, song, outputPath, mediaFormat, metadataResult.genre, cover, metadataResult.bpmKey, lyrics, trackGain)
 	warnings = append(warnings, finalizeWarnings...)
 
 	return downloadResult{
 		success:  true,
+		path:     outputPath,
+		quality:  strings.ToLower(mediaFormat),
+		lyrics:   lyricsStatus,
 		warnings: warnings,
 	}
 }
 
-func (c *Client) streamToFile(ctx context.Context, stream io.ReadCloser, outputPath string, key []byte) error {
+// openStream opens the media stream of a song, resuming the partial download
+// of a previous attempt at outputPath if there is one. The download starts
+// over when the CDN cannot resume it.
+func (c *Client) openStream(ctx context.Context, media *deezer.Media, songID, outputPath string) (io.ReadCloser, *partialInfo, error) {
+	partial := loadPartial(outputPath, songID, media.GetFormat())
+	stream, err := c.deezerClient.GetMediaStream(ctx, media, songID, partial.Bytes)
+	if errors.Is(err, deezer.ErrRangeNotSatisfiable) {
+		removePartial(outputPath)
+		partial = loadPartial(outputPath, songID, media.GetFormat())
+		stream, err = c.deezerClient.GetMediaStream(ctx, media, songID, 0)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return stream, partial, nil
+}
+
+// streamToFile decrypts the stream into a .part file next to outputPath and
+// renames it once complete. On failure the .part file is kept along with a
+// sidecar describing its progress, so that the next attempt can resume it.
+func (c *Client) streamToFile(ctx context.Context, stream io.ReadCloser, outputPath string, key []byte, partial *partialInfo) (err error) {
 	defer stream.Close()
 
-	file, err := os.Create(outputPath)
+	file, err := os.OpenFile(partPath(outputPath), os.O_CREATE|os.O_WRONLY, 0644)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	defer func() {
+		file.Close()
+		if err == nil {
+			return
+		}
+		if partial.Bytes == 0 {
+			removePartial(outputPath)
+			return
+		}
+		// Without an up to date sidecar, the next attempt would resume the
+		// .part file at the wrong offset
+		if saveErr := partial.save(outputPath); saveErr != nil {
+			removePartial(outputPath)
+			err = errors.Join(err, fmt.Errorf("failed to save download progress: %w", saveErr))
+		}
+	}()
+
+	if err := file.Truncate(partial.Bytes); err != nil {
+		return err
+	}
+	if _, err := file.Seek(partial.Bytes, io.SeekStart); err != nil {
+		return err
+	}
 
 	buffer := make([]byte, chunkSize)
-	for chunk := 0; ; chunk++ {
+	for chunk := partial.Chunks; ; chunk++ {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
@@ -226,16 +469,16 @@ func (c *Client) streamToFile(ctx context.Context, stream io.ReadCloser, outputP
 		totalRead := 0
 		for totalRead < chunkSize {
 			n, err := stream.Read(buffer[totalRead:])
+			if n > 0 {
+				totalRead += n
+			}
+
 			if err != nil {
 				if errors.Is(err, io.EOF) {
 					break
 				}
 				return err
 			}
-
-			if n > 0 {
-				totalRead += n
-			}
 		}
 
 		if totalRead == 0 {
@@ -257,30 +500,68 @@ func (c *Client) streamToFile(ctx context.Context, stream io.ReadCloser, outputP
 		if totalRead < chunkSize {
 			break
 		}
+
+		partial.Bytes += chunkSize
+		partial.Chunks++
+		if partial.Chunks%checkpointChunks == 0 {
+			if err := partial.save(outputPath); err != nil {
+				return fmt.Errorf("failed to save download progress: %w", err)
+			}
+		}
 	}
 
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(partPath(outputPath), outputPath); err != nil {
+		return err
+	}
+	fileutil.DeleteFile(sidecarPath(outputPath))
+
 	return nil
 }
 
-func (c *Client) finalizeDownload(resource deezer.Resource, song *deezer.Song, outputPath, mediaFormat, genre string, cover []byte, bpmKey provider.BPMKey) []string {
+func (c *Client) finalizeDownload(album *deezer.Album, resourceID string, song *deezer.Song, outputPath, mediaFormat, genre string, cover []byte, bpmKey provider.BPMKey, lyrics *deezer.Lyrics, replayGain bool) []string {
 	var warnings []string
 
-	if err := tags.AddTags(resource, song, cover, outputPath, bpmKey.BPM, bpmKey.Key, genre); err != nil {
+	if err := tags.AddTags(album, song, cover, outputPath, bpmKey.BPM, bpmKey.Key, genre, lyrics); err != nil {
 		warnings = append(warnings, fmt.Sprintf("failed to add tags: %v", err))
 	}
 
+	if lyrics != nil && len(lyrics.Synced) > 0 {
+		if err := tags.WriteLRC(outputPath, song, lyrics); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to write lyrics file: %v", err))
+		}
+	}
+
+	if replayGain {
+		if err := writeTrackReplayGain(outputPath); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to compute ReplayGain: %v", err))
+		}
+	}
+
 	hash, err := fileutil.GetFileHash(outputPath)
 	if err != nil {
 		warnings = append(warnings, fmt.Sprintf("failed to get file hash: %v", err))
 	}
 
+	source := store.Source(c.resourceType, resourceID)
 	info := &store.DownloadInfo{
 		SongID:     song.ID,
 		Quality:    mediaFormat,
 		Path:       outputPath,
 		Hash:       hash,
+		Source:     source,
+		Sources:    []string{source},
+		Profile:    c.appConfig.Profile,
 		Downloaded: time.Now(),
 	}
+	// The song still belongs to the resources it was downloaded for before
+	if existing, err := store.GetDownloadInfo(c.appConfig.Profile, song.ID); err == nil {
+		for _, owner := range existing.Owners() {
+			info.AddSource(owner)
+		}
+	}
 
 	if err := info.Save(); err != nil {
 		warnings = append(warnings, fmt.Sprintf("failed to save download info: %v", err))
@@ -289,9 +570,17 @@ func (c *Client) finalizeDownload(resource deezer.Resource, song *deezer.Song, o
 	return warnings
 }
 
-func (c *Client) initHashIndex(ctx context.Context) error {
+func (c *Client) outputDir(opts Options) string {
+	if opts.OutputDir != "" {
+		return opts.OutputDir
+	}
+
+	return c.appConfig.OutputDir
+}
+
+func (c *Client) initHashIndex(ctx context.Context, root string) error {
 	c.hashIndexOnce.Do(func() {
-		c.hashIndex, c.hashIndexErr = fileutil.NewHashIndex(ctx, c.appConfig.OutputDir)
+		c.hashIndex, c.hashIndexErr = fileutil.NewHashIndex(ctx, root)
 	})
 
 	return c.hashIndexErr
@@ -0,0 +1,66 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/mathismqn/godeez/internal/deezer"
+)
+
+// DefaultReleaseTypes are the release types downloaded with a discography
+// when none are given.
+var DefaultReleaseTypes = []string{"album", "ep", "single"}
+
+// RunDiscography downloads every release of an artist matching the release
+// types and dates of opts. Each release is downloaded as an album.
+func (b *Batch) RunDiscography(ctx context.Context, opts Options, artistID string) error {
+	c := b.newClient("album", nil)
+	if err := c.initDeezerClient(ctx, opts); err != nil {
+		return err
+	}
+
+	releases, err := c.deezerClient.FetchDiscography(ctx, artistID)
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 {
+		return fmt.Errorf("artist with ID %s has no releases", artistID)
+	}
+
+	var links []deezer.Link
+	for _, release := range releases {
+		if matchesRelease(opts, release) {
+			links = append(links, deezer.Link{Type: "album", ID: release.ID})
+		}
+	}
+	if len(links) == 0 {
+		return fmt.Errorf("no releases of %s match the given filters", releases[0].Artist)
+	}
+
+	b.Logger.Infof("Downloading %d of %d releases of %s\n", len(links), len(releases), releases[0].Artist)
+
+	return b.run(ctx, opts, c.deezerClient, "discography", releases[0].Artist, artistID, links)
+}
+
+func matchesRelease(opts Options, release *deezer.Release) bool {
+	releaseTypes := opts.ReleaseTypes
+	if len(releaseTypes) == 0 {
+		releaseTypes = DefaultReleaseTypes
+	}
+	if !slices.Contains(releaseTypes, release.ReleaseType()) {
+		return false
+	}
+
+	if opts.Since == "" && opts.Until == "" {
+		return true
+	}
+
+	date := release.ReleaseDate()
+	if date == "" {
+		return false
+	}
+
+	// Dates are formatted as YYYY-MM-DD, so they compare as strings
+	return (opts.Since == "" || date >= opts.Since) && (opts.Until == "" || date <= opts.Until)
+}
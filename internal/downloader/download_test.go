@@ -0,0 +1,315 @@
+package downloader_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/deezertest"
+	"github.com/mathismqn/godeez/internal/downloader"
+	"github.com/mathismqn/godeez/internal/replaygain"
+	"github.com/mathismqn/godeez/internal/store"
+	"github.com/mathismqn/godeez/internal/tags"
+)
+
+// flacHeaderSize is the size of the "fLaC" marker and STREAMINFO block
+// that start the files of deezertest.ToneFLAC, before the audio frames.
+const flacHeaderSize = 4 + 4 + 34
+
+type fixture struct {
+	srv       *deezertest.Server
+	appConfig *config.Config
+	mp3       []byte
+	flac      []byte
+}
+
+// newFixture serves an album of an MP3 song and a FLAC song, and opens the
+// database of a new home directory.
+func newFixture(t *testing.T) *fixture {
+	t.Helper()
+
+	f := &fixture{
+		srv:  deezertest.NewServer(),
+		mp3:  deezertest.SilentMP3(5),
+		flac: deezertest.ToneFLAC(2, 997, 0.5),
+	}
+	t.Cleanup(f.srv.Close)
+
+	f.srv.AddSong(&deezertest.Song{ID: "1", Title: "One More Time", Artist: "Daft Punk", AlbumID: "10", AlbumTitle: "Discovery",
+		TrackNumber: 1, Duration: 5, ISRC: "GBDUW0000053", Audio: map[string][]byte{"MP3_128": f.mp3}})
+	f.srv.AddSong(&deezertest.Song{ID: "2", Title: "Aerodynamic", Artist: "Daft Punk", AlbumID: "10", AlbumTitle: "Discovery",
+		TrackNumber: 2, Duration: 2, Audio: map[string][]byte{"FLAC": f.flac}})
+	f.srv.AddAlbum(&deezertest.Album{ID: "10", Title: "Discovery", Artist: "Daft Punk", Label: "Virgin",
+		ReleaseDate: "2001-03-12", Type: 1, SongIDs: []string{"1", "2"}})
+
+	home := t.TempDir()
+	f.appConfig = f.srv.Config(home)
+	if err := store.OpenDB(home, ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	return f
+}
+
+func (f *fixture) path(name string) string {
+	return filepath.Join(f.appConfig.OutputDir, "Daft Punk - Discovery", name)
+}
+
+func options() downloader.Options {
+	opts := downloader.DefaultOptions()
+	opts.Quality = "flac"
+
+	return opts
+}
+
+func readFile(t *testing.T, path string) []byte {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestRunAlbum(t *testing.T) {
+	f := newFixture(t)
+
+	if err := downloader.New(f.appConfig, "album").Run(context.Background(), options(), "10"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tags are written before the audio of MP3 files, and the audio
+	// frames of FLAC files are left as they are
+	mp3Path := f.path("1. Daft Punk - One More Time.mp3")
+	if !bytes.HasSuffix(readFile(t, mp3Path), f.mp3) {
+		t.Error("MP3 audio not decrypted")
+	}
+	flacPath := f.path("2. Daft Punk - Aerodynamic.flac")
+	if !bytes.HasSuffix(readFile(t, flacPath), f.flac[flacHeaderSize:]) {
+		t.Error("FLAC audio not decrypted")
+	}
+	if _, err := replaygain.Analyze(flacPath); err != nil {
+		t.Errorf("FLAC file cannot be decoded: %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want map[string]string
+	}{
+		{
+			path: mp3Path,
+			want: map[string]string{
+				"TITLE":       "One More Time",
+				"ARTIST":      "Daft Punk",
+				"ALBUM":       "Discovery",
+				"ALBUMARTIST": "Daft Punk",
+				"TRACKNUMBER": "1/2",
+				"DISCNUMBER":  "1/1",
+				"PUBLISHER":   "Virgin",
+				"ISRC":        "GBDUW0000053",
+			},
+		},
+		{
+			path: flacPath,
+			want: map[string]string{
+				"TITLE":       "Aerodynamic",
+				"ARTIST":      "Daft Punk",
+				"ALBUM":       "Discovery",
+				"ALBUMARTIST": "Daft Punk",
+				"TRACKNUMBER": "2",
+				"TOTALTRACKS": "2",
+				"DISCNUMBER":  "1",
+				"TOTALDISCS":  "1",
+				"PUBLISHER":   "Virgin",
+				"DATE":        "2001",
+			},
+		},
+	}
+	for _, tt := range tests {
+		fields, err := tags.Read(tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, want := range tt.want {
+			if fields[name] != want {
+				t.Errorf("%s: %s = %q, want %q", filepath.Base(tt.path), name, fields[name], want)
+			}
+		}
+		if fields["COVER"] == "" {
+			t.Errorf("%s: no cover", filepath.Base(tt.path))
+		}
+	}
+
+	for _, id := range []string{"1", "2"} {
+		if _, err := store.GetDownloadInfo(f.appConfig.Profile, id); err != nil {
+			t.Errorf("download of song %s not recorded: %v", id, err)
+		}
+	}
+}
+
+func TestRunResumesPartialDownload(t *testing.T) {
+	f := newFixture(t)
+
+	// A previous attempt stopped after two chunks. They are filled with a
+	// marker to tell them from the rest of the song.
+	const written = 2 * 2048
+	mp3Path := f.path("1. Daft Punk - One More Time.mp3")
+	marker := bytes.Repeat([]byte{0xAB}, written)
+	if err := os.MkdirAll(filepath.Dir(mp3Path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(mp3Path+".part", marker, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sidecar := `{"song_id":"1","format":"MP3_128","bytes":4096,"chunks":2}`
+	if err := os.WriteFile(mp3Path+".part.json", []byte(sidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := downloader.New(f.appConfig, "album").Run(context.Background(), options(), "10"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := append(marker, f.mp3[written:]...)
+	if !bytes.HasSuffix(readFile(t, mp3Path), want) {
+		t.Error("download not resumed after the written chunks")
+	}
+	for _, path := range []string{mp3Path + ".part", mp3Path + ".part.json"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s left behind", filepath.Base(path))
+		}
+	}
+}
+
+func TestRunRefreshesExpiredTokens(t *testing.T) {
+	f := newFixture(t)
+	ctx := context.Background()
+	opts := options()
+
+	c := downloader.New(f.appConfig, "album")
+	album, err := c.Fetch(ctx, opts, "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.srv.ExpireTokens()
+	if err := c.Download(ctx, opts, album, "10"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"1. Daft Punk - One More Time.mp3", "2. Daft Punk - Aerodynamic.flac"} {
+		if _, err := os.Stat(f.path(name)); err != nil {
+			t.Errorf("%s not downloaded: %v", name, err)
+		}
+	}
+	if logins := f.srv.Calls("deezer.getUserData"); logins != 2 {
+		t.Errorf("logged in %d times, want 2", logins)
+	}
+}
+
+func TestRunPlaylistAlbumFields(t *testing.T) {
+	f := newFixture(t)
+	f.srv.AddPlaylist(&deezertest.Playlist{ID: "20", Title: "Road Trip", Creator: "me", SongIDs: []string{"2"}})
+
+	opts := options()
+	opts.Template = "{albumartist}/{year} - {album} [{label}]/{artist} - {title}"
+	if err := downloader.New(f.appConfig, "playlist").Run(context.Background(), opts, "20"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The album fields come from the album of the song, not the playlist
+	path := filepath.Join(f.appConfig.OutputDir, "Daft Punk", "2001 - Discovery [Virgin]", "Daft Punk - Aerodynamic.flac")
+	if _, err := os.Stat(path); err != nil {
+		t.Error(err)
+	}
+}
+
+// captureStdout returns what run writes to the standard output.
+func captureStdout(t *testing.T, run func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- data
+	}()
+	run()
+	w.Close()
+
+	return string(<-out)
+}
+
+func TestRunReportsRetries(t *testing.T) {
+	f := newFixture(t)
+	f.srv.Fail("deezer.pageAlbum", http.StatusServiceUnavailable)
+
+	opts := options()
+	opts.Output = downloader.OutputJSON
+	opts.RetryBackoff = time.Millisecond
+	var runErr error
+	out := captureStdout(t, func() {
+		runErr = downloader.New(f.appConfig, "album").Run(context.Background(), opts, "10")
+	})
+	if runErr != nil {
+		t.Fatal(runErr)
+	}
+
+	// The retried fetch of the album is reported before its songs
+	var warnings []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var event struct {
+			Event   string `json:"event"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event %q: %v", line, err)
+		}
+		if event.Event == "warning" {
+			warnings = append(warnings, event.Message)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "503") {
+		t.Errorf("warnings = %q, want the retry of the album", warnings)
+	}
+}
+
+func TestBatchRetriesFailedSongs(t *testing.T) {
+	f := newFixture(t)
+	f.srv.AddPlaylist(&deezertest.Playlist{ID: "20", Title: "Road Trip", Creator: "me", SongIDs: []string{"1", "2"}})
+	f.srv.Fail("get_url", http.StatusInternalServerError)
+
+	opts := options()
+	opts.Retries = 0
+	links := []deezer.Link{{Type: "album", ID: "10"}, {Type: "playlist", ID: "20"}}
+	if err := downloader.NewBatch(f.appConfig).Run(context.Background(), opts, "links.txt", links); err != nil {
+		t.Fatal(err)
+	}
+
+	// The song that failed in the album is downloaded with the playlist,
+	// while the one downloaded with the album is not fetched again
+	if _, err := os.Stat(filepath.Join(f.appConfig.OutputDir, "Road Trip", "Daft Punk - One More Time.mp3")); err != nil {
+		t.Error(err)
+	}
+	if calls := f.srv.Calls("get_url"); calls != 3 {
+		t.Errorf("media URLs fetched %d times, want 3", calls)
+	}
+}
@@ -0,0 +1,137 @@
+package downloader
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/mathismqn/godeez/internal/deezer"
+)
+
+// Events emitted in JSON output mode, one JSON object per line.
+const (
+	eventStart   = "start"
+	eventSong    = "song"
+	eventWarning = "warning"
+	eventSummary = "summary"
+)
+
+// Song statuses reported by song events.
+const (
+	statusDownloaded = "downloaded"
+	statusSkipped    = "skipped"
+	statusFailed     = "failed"
+)
+
+type startEvent struct {
+	Event      string `json:"event"`
+	Type       string `json:"type"`
+	ID         string `json:"id"`
+	Title      string `json:"title"`
+	TotalSongs int    `json:"total_songs"`
+}
+
+type songEvent struct {
+	Event    string   `json:"event"`
+	Index    int      `json:"index"`
+	Total    int      `json:"total"`
+	Status   string   `json:"status"`
+	SongID   string   `json:"song_id"`
+	Artist   string   `json:"artist"`
+	Title    string   `json:"title"`
+	Path     string   `json:"path,omitempty"`
+	Quality  string   `json:"quality,omitempty"`
+	Lyrics   string   `json:"lyrics,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+type warningEvent struct {
+	Event   string `json:"event"`
+	Message string `json:"message"`
+}
+
+type summaryEvent struct {
+	Event          string  `json:"event"`
+	Type           string  `json:"type"`
+	ID             string  `json:"id"`
+	Title          string  `json:"title"`
+	Downloaded     int     `json:"downloaded"`
+	Skipped        int     `json:"skipped"`
+	Failed         int     `json:"failed"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	OutputDir      string  `json:"output_dir"`
+	// Lyrics is only set when lyrics were requested
+	Lyrics *lyricsCounts `json:"lyrics,omitempty"`
+}
+
+type lyricsCounts struct {
+	Synced  int `json:"synced"`
+	Plain   int `json:"plain"`
+	Missing int `json:"missing"`
+}
+
+// eventWriter writes JSON Lines events. Callers are expected to serialize
+// writes, which the progressTracker does through its mutex.
+type eventWriter struct {
+	enc *json.Encoder
+}
+
+func newEventWriter(w io.Writer) *eventWriter {
+	return &eventWriter{enc: json.NewEncoder(w)}
+}
+
+func (w *eventWriter) emit(event any) {
+	// A failed write to stdout cannot be reported anywhere more useful
+	_ = w.enc.Encode(event)
+}
+
+func newSongEvent(index, total int, song *deezer.Song, result downloadResult) songEvent {
+	event := songEvent{
+		Event:    eventSong,
+		Index:    index + 1,
+		Total:    total,
+		SongID:   song.ID,
+		Artist:   song.Artist,
+		Title:    song.GetTitle(),
+		Path:     result.path,
+		Quality:  result.quality,
+		Lyrics:   result.lyrics,
+		Warnings: result.warnings,
+	}
+
+	switch {
+	case result.skipped:
+		event.Status = statusSkipped
+	case result.err != nil:
+		event.Status = statusFailed
+		event.Error = result.err.Error()
+	default:
+		event.Status = statusDownloaded
+	}
+
+	return event
+}
+
+func newSummaryEvent(resourceType, resourceID, resourceTitle, outputDir string, stats *downloadStats, elapsed time.Duration) summaryEvent {
+	event := summaryEvent{
+		Event:          eventSummary,
+		Type:           resourceType,
+		ID:             resourceID,
+		Title:          resourceTitle,
+		Downloaded:     stats.downloaded,
+		Skipped:        stats.skipped,
+		Failed:         stats.failed,
+		ElapsedSeconds: elapsed.Seconds(),
+		OutputDir:      outputDir,
+	}
+	if stats.hasLyrics() {
+		event.Lyrics = &lyricsCounts{
+			Synced:  stats.lyricsSynced,
+			Plain:   stats.lyricsPlain,
+			Missing: stats.lyricsMissing,
+		}
+	}
+
+	return event
+}
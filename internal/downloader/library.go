@@ -0,0 +1,41 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mathismqn/godeez/internal/deezer"
+)
+
+// RunLibrary downloads the favourite tracks of the user when favorites is
+// set, then every resource of the given collections. Each album and
+// playlist is downloaded to its own folder.
+func (b *Batch) RunLibrary(ctx context.Context, opts Options, favorites bool, collections ...deezer.Collection) error {
+	c := b.newClient("favorites", nil)
+	if err := c.initDeezerClient(ctx, opts); err != nil {
+		return err
+	}
+	session := c.deezerClient.Session
+
+	var links []deezer.Link
+	if favorites {
+		links = append(links, deezer.Link{Type: "favorites", ID: session.UserID})
+	}
+
+	title := "Library"
+	for _, collection := range collections {
+		if err := c.deezerClient.FetchCollection(ctx, collection); err != nil {
+			return fmt.Errorf("failed to fetch %s: %w", collection.GetTitle(), err)
+		}
+		links = append(links, collection.GetLinks()...)
+		if len(collections) == 1 {
+			title = collection.GetTitle()
+		}
+	}
+
+	if len(links) == 0 {
+		return fmt.Errorf("library is empty")
+	}
+
+	return b.run(ctx, opts, c.deezerClient, "library", title, session.UserID, links)
+}
@@ -0,0 +1,32 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mathismqn/godeez/internal/deezer"
+)
+
+// Lyrics availability reported for the songs downloaded with lyrics.
+const (
+	lyricsSynced  = "synced"
+	lyricsPlain   = "plain"
+	lyricsMissing = "missing"
+)
+
+// fetchLyrics returns the lyrics of a song and their availability. The
+// lyrics are nil when the song has none or they could not be fetched, in
+// which case the error is returned as well.
+func (c *Client) fetchLyrics(ctx context.Context, song *deezer.Song) (*deezer.Lyrics, string, error) {
+	lyrics, err := c.deezerClient.FetchLyrics(ctx, song.ID)
+	switch {
+	case errors.Is(err, deezer.ErrNoLyrics):
+		return nil, lyricsMissing, nil
+	case err != nil:
+		return nil, lyricsMissing, err
+	case len(lyrics.Synced) > 0:
+		return lyrics, lyricsSynced, nil
+	default:
+		return lyrics, lyricsPlain, nil
+	}
+}
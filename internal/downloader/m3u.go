@@ -0,0 +1,84 @@
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/flytam/filenamify"
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/fileutil"
+	"github.com/mathismqn/godeez/internal/store"
+	"github.com/mathismqn/godeez/internal/template"
+)
+
+// WritePlaylistFile writes an extended M3U8 file listing the songs of the
+// resource in order, next to the folder where its songs are downloaded.
+// Songs that were downloaded elsewhere are referenced where they are, and
+// songs that were never downloaded are left out.
+func (c *Client) WritePlaylistFile(opts Options, resource deezer.Resource) (string, error) {
+	tmpl, err := c.pathTemplate(opts, resource)
+	if err != nil {
+		return "", err
+	}
+
+	dir := commonDir(c.songPaths(opts, resource, tmpl))
+	if err := fileutil.EnsureDir(dir); err != nil {
+		return "", err
+	}
+
+	name, _ := filenamify.Filenamify(resource.GetTitle(), filenamify.Options{MaxLength: 250})
+	playlistPath := filepath.Join(dir, name+".m3u8")
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "#EXTM3U\n#PLAYLIST:%s\n", resource.GetTitle())
+	for _, song := range resource.GetSongs() {
+		info, err := store.GetDownloadInfo(c.appConfig.Profile, song.ID)
+		if err != nil || !fileutil.FileExists(info.Path) {
+			continue
+		}
+
+		entryPath := info.Path
+		if rel, err := filepath.Rel(dir, info.Path); err == nil {
+			entryPath = filepath.ToSlash(rel)
+		}
+
+		duration := song.Duration
+		if duration == "" {
+			duration = "-1"
+		}
+		fmt.Fprintf(&b, "#EXTINF:%s,%s - %s\n%s\n", duration, song.Artist, song.GetTitle(), entryPath)
+	}
+
+	return playlistPath, os.WriteFile(playlistPath, []byte(b.String()), 0644)
+}
+
+// songPaths renders the paths of the songs of a resource with the albums
+// fetched while downloading them, without fetching the others.
+func (c *Client) songPaths(opts Options, resource deezer.Resource, tmpl *template.Template) []string {
+	songs := resource.GetSongs()
+	paths := make([]string, len(songs))
+	for i, song := range songs {
+		paths[i] = c.renderPath(opts, resource, tmpl, c.fetchedAlbum(resource, song), song)
+	}
+
+	return paths
+}
+
+// songPath renders the path of a song, without its file extension. The
+// album of the song is fetched when the template uses its fields, and the
+// song's own fields are used when it cannot be fetched.
+func (c *Client) songPath(ctx context.Context, opts Options, resource deezer.Resource, tmpl *template.Template, song *deezer.Song) string {
+	var album *deezer.Album
+	if tmpl.Uses(albumFields...) {
+		album, _ = c.songAlbum(ctx, resource, song)
+	}
+
+	return c.renderPath(opts, resource, tmpl, album, song)
+}
+
+func (c *Client) renderPath(opts Options, resource deezer.Resource, tmpl *template.Template, album *deezer.Album, song *deezer.Song) string {
+	return filepath.Join(c.outputDir(opts), tmpl.Render(songFields(c.resourceType, resource, album, song)))
+}
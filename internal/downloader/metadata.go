@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/mathismqn/godeez/internal/config"
 	"github.com/mathismqn/godeez/internal/deezer"
 	"github.com/mathismqn/godeez/internal/provider"
 )
@@ -18,11 +19,13 @@ type metadataResult struct {
 
 type metadataFetcher struct {
 	httpClient *http.Client
+	endpoints  config.Endpoints
 }
 
-func newMetadataFetcher(httpClient *http.Client) *metadataFetcher {
+func newMetadataFetcher(httpClient *http.Client, endpoints config.Endpoints) *metadataFetcher {
 	return &metadataFetcher{
 		httpClient: httpClient,
+		endpoints:  endpoints,
 	}
 }
 
@@ -44,7 +47,7 @@ func (mf *metadataFetcher) fetch(ctx context.Context, song *deezer.Song, opts Op
 
 	if opts.BPM {
 		go func() {
-			p := provider.BPMProvider{}
+			p := provider.BPMProvider{BaseURL: mf.endpoints.SongBPM}
 			bmpKey, err := p.Fetch(ctx, mf.httpClient, song.Artist, song.Title, song.Duration)
 			if err != nil {
 				bmpErrChan <- err
@@ -56,7 +59,7 @@ func (mf *metadataFetcher) fetch(ctx context.Context, song *deezer.Song, opts Op
 
 	if opts.Genre {
 		go func() {
-			p := provider.GenreProvider{}
+			p := provider.GenreProvider{BaseURL: mf.endpoints.LastFM}
 			genre, err := p.Fetch(ctx, mf.httpClient, song.Artist, song.GetTitle())
 			if err != nil {
 				genreErrChan <- err
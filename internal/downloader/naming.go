@@ -0,0 +1,174 @@
+package downloader
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/fileutil"
+	"github.com/mathismqn/godeez/internal/store"
+	"github.com/mathismqn/godeez/internal/template"
+)
+
+// defaultTemplates keep the layout used before templates were configurable,
+// with track numbers as given by Deezer, so that existing libraries are not
+// renamed. Albums with several discs use multiDiscAlbumTemplate instead.
+var defaultTemplates = map[string]string{
+	"album":     "{albumartist} - {album}/{tracknumber}. {artist} - {title}",
+	"playlist":  "{resource}/{artist} - {title}",
+	"artist":    "{resource}/{artist} - {title}",
+	"track":     "Singles/{artist} - {title}",
+	"favorites": "{resource}/{artist} - {title}",
+}
+
+// multiDiscAlbumTemplate is the default template of albums with several
+// discs, whose track numbers start over on each disc and used to collide.
+const multiDiscAlbumTemplate = "{albumartist} - {album}/{disc}-{track}. {artist} - {title}"
+
+// pathTemplate returns the template given in the options, then the one from the
+// config, then the default one for the resource type.
+func (c *Client) pathTemplate(opts Options, resource deezer.Resource) (*template.Template, error) {
+	switch {
+	case opts.Template != "":
+		return template.Parse(opts.Template)
+	case c.appConfig.Template != "":
+		return template.Parse(c.appConfig.Template)
+	case isMultiDisc(resource):
+		return template.Parse(multiDiscAlbumTemplate)
+	default:
+		return template.Parse(defaultTemplates[c.resourceType])
+	}
+}
+
+func isMultiDisc(resource deezer.Resource) bool {
+	album, ok := resource.(*deezer.Album)
+	if !ok {
+		return false
+	}
+	discs, err := strconv.Atoi(album.TotalDiscs())
+
+	return err == nil && discs > 1
+}
+
+// albumFields are the placeholders filled from the album of a song.
+var albumFields = []string{"albumartist", "album", "date", "year", "label", "totaltracks", "totaldiscs"}
+
+// songFields returns the values of the placeholders for a song. The album
+// fields come from album, the album of the song whatever the resource, and
+// from the song itself when album is nil.
+func songFields(resourceType string, resource deezer.Resource, album *deezer.Album, song *deezer.Song) map[string]string {
+	artists := strings.Join(song.Contributors.MainArtists, ", ")
+	if artists == "" {
+		artists = song.Artist
+	}
+
+	fields := map[string]string{
+		"id":          song.ID,
+		"title":       song.GetTitle(),
+		"version":     song.Version,
+		"artist":      song.Artist,
+		"artists":     artists,
+		"albumartist": song.Artist,
+		"album":       song.AlbumTitle,
+		"track":       padNumber(song.TrackNumber),
+		"tracknumber": song.TrackNumber,
+		"disc":        song.DiskNumber,
+		"date":        song.ReleaseDate,
+		"isrc":        song.ISRC,
+		"resource":    resource.GetTitle(),
+		"type":        resourceType,
+	}
+
+	if album != nil {
+		data := album.Results.Data
+		for name, value := range map[string]string{
+			"albumartist": data.Artist,
+			"album":       data.Title,
+			"date":        data.PhysicalReleaseDate,
+			"label":       data.Label,
+			"totaltracks": album.TotalTracks(),
+			"totaldiscs":  album.TotalDiscs(),
+		} {
+			if value != "" {
+				fields[name] = value
+			}
+		}
+	}
+
+	if len(fields["date"]) >= 4 {
+		fields["year"] = fields["date"][:4]
+	}
+
+	return fields
+}
+
+func padNumber(number string) string {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return number
+	}
+
+	return fmt.Sprintf("%02d", n)
+}
+
+func fileExtension(mediaFormat string) string {
+	if mediaFormat == "FLAC" {
+		return ".flac"
+	}
+
+	return ".mp3"
+}
+
+// reservePath returns outputPath, or "name (2).ext", "name (3).ext"... if it
+// is already used by another song on disk or in this run.
+func (c *Client) reservePath(songID, outputPath string) string {
+	c.pathsMu.Lock()
+	defer c.pathsMu.Unlock()
+
+	ext := filepath.Ext(outputPath)
+	base := strings.TrimSuffix(outputPath, ext)
+	for n := 1; ; n++ {
+		candidate := outputPath
+		if n > 1 {
+			candidate = fmt.Sprintf("%s (%d)%s", base, n, ext)
+		}
+
+		if owner, ok := c.paths[candidate]; ok && owner != songID {
+			continue
+		}
+		if fileutil.FileExists(candidate) && !ownsPath(c.appConfig.Profile, songID, candidate) {
+			continue
+		}
+
+		c.paths[candidate] = songID
+		return candidate
+	}
+}
+
+func ownsPath(profile, songID, path string) bool {
+	info, err := store.GetDownloadInfo(profile, songID)
+
+	return err == nil && info.Path == path
+}
+
+// commonDir returns the deepest directory containing all the given paths.
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	dir := filepath.Dir(paths[0])
+	for _, p := range paths[1:] {
+		for dir != filepath.Dir(dir) {
+			rel, err := filepath.Rel(dir, p)
+			if err == nil && !strings.HasPrefix(rel, "..") {
+				break
+			}
+			dir = filepath.Dir(dir)
+		}
+	}
+
+	return dir
+}
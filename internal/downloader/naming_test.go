@@ -0,0 +1,91 @@
+package downloader
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/template"
+)
+
+func TestPadNumber(t *testing.T) {
+	tests := map[string]string{
+		"":    "",
+		"3":   "03",
+		"12":  "12",
+		"123": "123",
+		"A1":  "A1",
+	}
+
+	for number, want := range tests {
+		if got := padNumber(number); got != want {
+			t.Errorf("padNumber(%q) = %q, want %q", number, got, want)
+		}
+	}
+}
+
+func TestSongFieldsNumbers(t *testing.T) {
+	tmpl, err := template.Parse(multiDiscAlbumTemplate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	song := &deezer.Song{ID: "1", Artist: "Daft Punk", Title: "Voyager", AlbumTitle: "Discovery", TrackNumber: "9", DiskNumber: "2"}
+	got := tmpl.Render(songFields("playlist", &deezer.Playlist{}, nil, song))
+	if want := filepath.FromSlash("Daft Punk - Discovery/2-09. Daft Punk - Voyager"); got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestDefaultAlbumTemplate(t *testing.T) {
+	// The file names of albums downloaded before templates were
+	// configurable
+	tmpl, err := template.Parse(defaultTemplates["album"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	album := &deezer.Album{}
+	album.Results.Data.Artist = "Daft Punk"
+	album.Results.Data.Title = "Discovery"
+	song := &deezer.Song{ID: "1", Artist: "Daft Punk", Title: "One More Time", AlbumTitle: "Discovery", TrackNumber: "1"}
+	got := tmpl.Render(songFields("album", album, album, song))
+	if want := filepath.FromSlash("Daft Punk - Discovery/1. Daft Punk - One More Time"); got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+}
+
+func TestSongFieldsAlbum(t *testing.T) {
+	tmpl, err := template.Parse("{albumartist}/{year} - {album}/{label} - {disc}of{totaldiscs} {tracknumber}of{totaltracks} - {artist} - {title}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A song of a compilation downloaded with a playlist
+	album := &deezer.Album{}
+	album.Results.Data.Artist = "Various Artists"
+	album.Results.Data.Title = "Ministry of Sound"
+	album.Results.Data.PhysicalReleaseDate = "2001-11-05"
+	album.Results.Data.Label = "MOS"
+	album.Results.Data.TrackCount = "40"
+	album.Results.Data.DiskCount = "2"
+	song := &deezer.Song{ID: "1", Artist: "Daft Punk", Title: "Digital Love", AlbumTitle: "Ministry of Sound", ReleaseDate: "2001-03-12", TrackNumber: "7", DiskNumber: "1"}
+
+	tests := []struct {
+		name  string
+		album *deezer.Album
+		want  string
+	}{
+		{"with album", album, "Various Artists/2001 - Ministry of Sound/MOS - 1of2 7of40 - Daft Punk - Digital Love"},
+		{"album not fetched", nil, "Daft Punk/2001 - Ministry of Sound/1of 7of - Daft Punk - Digital Love"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tmpl.Render(songFields("playlist", &deezer.Playlist{}, tt.album, song))
+			if want := filepath.FromSlash(tt.want); got != want {
+				t.Errorf("Render() = %q, want %q", got, want)
+			}
+		})
+	}
+}
@@ -2,7 +2,32 @@ package downloader
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/template"
+)
+
+const (
+	DefaultQuality     = "mp3_320"
+	DefaultTimeout     = 2 * time.Minute
+	DefaultLimit       = 10
+	DefaultConcurrency = 1
+
+	DefaultRetries      = 3
+	DefaultRetryBackoff = time.Second
+	maxRetries          = 10
+
+	maxConcurrency = 10
+)
+
+// Output modes of a download run.
+const (
+	OutputText = "text"
+	OutputJSON = "json"
 )
 
 var validQualities = map[string]bool{
@@ -12,12 +37,85 @@ var validQualities = map[string]bool{
 }
 
 type Options struct {
-	Quality string
-	Timeout time.Duration
-	Limit   int
-	BPM     bool
-	Genre   bool
-	Strict  bool
+	Quality     string
+	Timeout     time.Duration
+	Limit       int
+	Concurrency int
+	// Retries is the number of times a failed request is retried, waiting
+	// RetryBackoff before the first retry and twice as long on each retry
+	Retries      int
+	RetryBackoff time.Duration
+	BPM          bool
+	Genre        bool
+	Strict       bool
+	// Lyrics embeds the lyrics of each song and writes its synchronised
+	// lyrics to a .lrc file next to it
+	Lyrics bool
+	// ReplayGain analyses the loudness of each song and writes its
+	// ReplayGain tags, with album values for albums
+	ReplayGain bool
+	// OutputDir overrides the output directory of the config when set
+	OutputDir string
+	// Template is the filename template used for this download
+	Template string
+	// Output is either OutputText (the default) or OutputJSON
+	Output string
+	// Discography downloads every release of an artist instead of its top
+	// songs, keeping those whose type is in ReleaseTypes and whose release
+	// date (YYYY-MM-DD) is between Since and Until when they are set
+	Discography  bool
+	ReleaseTypes []string
+	Since        string
+	Until        string
+}
+
+// DefaultOptions returns the options used when neither a flag nor the
+// config sets them.
+func DefaultOptions() Options {
+	return Options{
+		Quality:      DefaultQuality,
+		Timeout:      DefaultTimeout,
+		Limit:        DefaultLimit,
+		Concurrency:  DefaultConcurrency,
+		Retries:      DefaultRetries,
+		RetryBackoff: DefaultRetryBackoff,
+		Output:       OutputText,
+		ReleaseTypes: DefaultReleaseTypes,
+	}
+}
+
+// ConfigOptions returns the options used when no flag is given: the
+// built-in defaults overridden by the config.
+func ConfigOptions(d config.Download) Options {
+	o := DefaultOptions()
+	o.ApplyConfig(d, func(string) bool { return false })
+	o.Quality = strings.ToLower(o.Quality)
+	o.Output = strings.ToLower(o.Output)
+
+	return o
+}
+
+// ApplyConfig sets the options to their value in the config, except those
+// whose flag was given, as reported by given.
+func (o *Options) ApplyConfig(d config.Download, given func(flag string) bool) {
+	setDefault(given, "quality", &o.Quality, d.Quality)
+	setDefault(given, "timeout", &o.Timeout, d.Timeout)
+	setDefault(given, "concurrency", &o.Concurrency, d.Concurrency)
+	setDefault(given, "retries", &o.Retries, d.Retries)
+	setDefault(given, "retry-backoff", &o.RetryBackoff, d.RetryBackoff)
+	setDefault(given, "bpm", &o.BPM, d.BPM)
+	setDefault(given, "genre", &o.Genre, d.Genre)
+	setDefault(given, "strict", &o.Strict, d.Strict)
+	setDefault(given, "lyrics", &o.Lyrics, d.Lyrics)
+	setDefault(given, "replaygain", &o.ReplayGain, d.ReplayGain)
+	setDefault(given, "limit", &o.Limit, d.Limit)
+	setDefault(given, "output", &o.Output, d.Output)
+}
+
+func setDefault[T any](given func(string) bool, flag string, dst *T, value *T) {
+	if value != nil && !given(flag) {
+		*dst = *value
+	}
 }
 
 func (o *Options) Validate() error {
@@ -33,6 +131,50 @@ func (o *Options) Validate() error {
 	if o.Limit > 100 {
 		return fmt.Errorf("limit must not exceed 100")
 	}
+	if o.Template != "" {
+		if _, err := template.Parse(o.Template); err != nil {
+			return err
+		}
+	}
+	if o.Output != "" && o.Output != OutputText && o.Output != OutputJSON {
+		return fmt.Errorf("invalid output option: %s", o.Output)
+	}
+	for _, releaseType := range o.ReleaseTypes {
+		if !slices.Contains(deezer.ReleaseTypes, releaseType) {
+			return fmt.Errorf("invalid release type: %s", releaseType)
+		}
+	}
+	for _, date := range []string{o.Since, o.Until} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return fmt.Errorf("invalid date %q, expected YYYY-MM-DD", date)
+		}
+	}
+	if o.Retries < 0 || o.Retries > maxRetries {
+		return fmt.Errorf("retries must be between 0 and %d", maxRetries)
+	}
+	if o.Retries > 0 && o.RetryBackoff <= 0 {
+		return fmt.Errorf("retry backoff must be a positive duration")
+	}
+	if o.Concurrency <= 0 {
+		return fmt.Errorf("concurrency must be a positive integer")
+	}
+	if o.Concurrency > maxConcurrency {
+		return fmt.Errorf("concurrency must not exceed %d", maxConcurrency)
+	}
 
 	return nil
 }
+
+// retryPolicy returns the retry policy of the HTTP requests.
+func (o *Options) retryPolicy() deezer.RetryPolicy {
+	policy := deezer.DefaultRetryPolicy
+	policy.Retries = o.Retries
+	if o.RetryBackoff > 0 {
+		policy.Backoff = o.RetryBackoff
+	}
+
+	return policy
+}
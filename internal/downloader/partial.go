@@ -0,0 +1,74 @@
+package downloader
+
+import (
+	"encoding/json"
+	"os"
+
+	"github.com/mathismqn/godeez/internal/fileutil"
+)
+
+// checkpointChunks is how often, in chunks, the sidecar is refreshed while
+// streaming so that a crash loses at most this much progress.
+const checkpointChunks = 512
+
+// partialInfo is stored next to a .part file and records how much of the
+// stream has already been decrypted and written. Bytes is always a multiple
+// of chunkSize so that the BF_CBC_STRIPE schedule (every third chunk is
+// encrypted) can be resumed from Chunks.
+type partialInfo struct {
+	SongID string `json:"song_id"`
+	Format string `json:"format"`
+	Bytes  int64  `json:"bytes"`
+	Chunks int64  `json:"chunks"`
+}
+
+func partPath(outputPath string) string {
+	return outputPath + ".part"
+}
+
+func sidecarPath(outputPath string) string {
+	return outputPath + ".part.json"
+}
+
+// loadPartial returns the progress of a previous attempt for the given song,
+// or a fresh partialInfo if there is nothing usable to resume from.
+func loadPartial(outputPath, songID, format string) *partialInfo {
+	fresh := &partialInfo{SongID: songID, Format: format}
+
+	data, err := os.ReadFile(sidecarPath(outputPath))
+	if err != nil {
+		return fresh
+	}
+
+	var info partialInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		removePartial(outputPath)
+		return fresh
+	}
+
+	stat, err := os.Stat(partPath(outputPath))
+	if err != nil ||
+		info.SongID != songID ||
+		info.Format != format ||
+		info.Bytes != info.Chunks*chunkSize ||
+		stat.Size() < info.Bytes {
+		removePartial(outputPath)
+		return fresh
+	}
+
+	return &info
+}
+
+func (p *partialInfo) save(outputPath string) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(sidecarPath(outputPath), data, 0644)
+}
+
+func removePartial(outputPath string) {
+	fileutil.DeleteFile(partPath(outputPath))
+	fileutil.DeleteFile(sidecarPath(outputPath))
+}
@@ -0,0 +1,181 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mathismqn/godeez/internal/crypto"
+	"github.com/mathismqn/godeez/internal/deezer"
+)
+
+const testSecretKey = "0123456789abcdef"
+
+// stripedStream returns audio encrypted as a BF_CBC_STRIPE stream.
+func stripedStream(t *testing.T, audio, key []byte) []byte {
+	t.Helper()
+
+	var stream []byte
+	for i := 0; i < len(audio); i += chunkSize {
+		chunk := audio[i:min(i+chunkSize, len(audio))]
+		if (i/chunkSize)%3 == 0 && len(chunk) == chunkSize {
+			encrypted, err := crypto.Encrypt(chunk, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chunk = encrypted
+		}
+		stream = append(stream, chunk...)
+	}
+
+	return stream
+}
+
+// brokenReader returns the first n bytes of data, then fails.
+type brokenReader struct {
+	data []byte
+	n    int
+}
+
+func (r *brokenReader) Read(p []byte) (int, error) {
+	if r.n == 0 {
+		return 0, errors.New("connection reset")
+	}
+	n := copy(p, r.data[:min(r.n, len(r.data))])
+	r.data = r.data[n:]
+	r.n -= n
+
+	return n, nil
+}
+
+func testMedia(t *testing.T, url string) *deezer.Media {
+	t.Helper()
+
+	var media deezer.Media
+	data := `{"data":[{"media":[{"format":"MP3_128","sources":[{"url":"` + url + `"}]}]}]}`
+	if err := json.Unmarshal([]byte(data), &media); err != nil {
+		t.Fatal(err)
+	}
+
+	return &media
+}
+
+func TestResumeDownload(t *testing.T) {
+	tests := []struct {
+		name string
+		// serve answers a request for stream
+		serve      func(w http.ResponseWriter, r *http.Request, stream []byte)
+		wantOffset int64
+	}{
+		{
+			name: "partial content",
+			serve: func(w http.ResponseWriter, r *http.Request, stream []byte) {
+				http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(stream))
+			},
+			wantOffset: 3 * chunkSize,
+		},
+		{
+			name: "range ignored",
+			serve: func(w http.ResponseWriter, r *http.Request, stream []byte) {
+				w.Write(stream)
+			},
+			wantOffset: 3 * chunkSize,
+		},
+		{
+			name: "range not satisfiable",
+			serve: func(w http.ResponseWriter, r *http.Request, stream []byte) {
+				if r.Header.Get("Range") != "" {
+					w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+					return
+				}
+				w.Write(stream)
+			},
+			wantOffset: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audio := make([]byte, 10*chunkSize+100)
+			rand.New(rand.NewSource(1)).Read(audio)
+			key := crypto.GetKey(testSecretKey, "1")
+			stream := stripedStream(t, audio, key)
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.serve(w, r, stream)
+			}))
+			defer srv.Close()
+
+			c := &Client{deezerClient: &deezer.Client{Session: &deezer.Session{HttpClient: srv.Client()}}}
+			media := testMedia(t, srv.URL)
+			outputPath := filepath.Join(t.TempDir(), "song.mp3")
+			ctx := context.Background()
+
+			// The first attempt fails in the middle of the fourth chunk
+			partial := loadPartial(outputPath, "1", media.GetFormat())
+			broken := io.NopCloser(&brokenReader{data: stream, n: 3*chunkSize + 100})
+			if err := c.streamToFile(ctx, broken, outputPath, key, partial); err == nil {
+				t.Fatal("streamToFile succeeded with a broken stream")
+			}
+			if _, err := os.Stat(sidecarPath(outputPath)); err != nil {
+				t.Fatalf("sidecar not saved: %v", err)
+			}
+
+			resumed, partial, err := c.openStream(ctx, media, "1", outputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if partial.Bytes != tt.wantOffset {
+				t.Errorf("resumed at %d, want %d", partial.Bytes, tt.wantOffset)
+			}
+			if err := c.streamToFile(ctx, resumed, outputPath, key, partial); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := os.ReadFile(outputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, audio) {
+				t.Error("resumed file differs from the audio")
+			}
+			for _, path := range []string{partPath(outputPath), sidecarPath(outputPath)} {
+				if _, err := os.Stat(path); !os.IsNotExist(err) {
+					t.Errorf("%s left behind", filepath.Base(path))
+				}
+			}
+		})
+	}
+}
+
+func TestStreamToFileUnsavedProgress(t *testing.T) {
+	audio := make([]byte, 4*chunkSize)
+	key := crypto.GetKey(testSecretKey, "1")
+	stream := stripedStream(t, audio, key)
+	outputPath := filepath.Join(t.TempDir(), "song.mp3")
+	// A directory in place of the sidecar makes saving it fail
+	if err := os.Mkdir(sidecarPath(outputPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{}
+	partial := &partialInfo{SongID: "1", Format: "MP3_128"}
+	broken := io.NopCloser(&brokenReader{data: stream, n: 2 * chunkSize})
+	err := c.streamToFile(context.Background(), broken, outputPath, key, partial)
+	if err == nil || !strings.Contains(err.Error(), "failed to save download progress") {
+		t.Fatalf("got error %v, want a download progress error", err)
+	}
+	if _, err := os.Stat(partPath(outputPath)); !os.IsNotExist(err) {
+		t.Error(".part file kept without a sidecar")
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"math/rand"
 	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/briandowns/spinner"
@@ -15,53 +18,260 @@ type downloadStats struct {
 	downloaded int
 	skipped    int
 	failed     int
+	// Lyrics availability of the downloaded songs, counted when lyrics are
+	// requested
+	lyricsSynced  int
+	lyricsPlain   int
+	lyricsMissing int
+}
+
+func (s *downloadStats) add(other *downloadStats) {
+	s.downloaded += other.downloaded
+	s.skipped += other.skipped
+	s.failed += other.failed
+	s.lyricsSynced += other.lyricsSynced
+	s.lyricsPlain += other.lyricsPlain
+	s.lyricsMissing += other.lyricsMissing
+}
+
+// addLyrics counts the lyrics availability of a downloaded song.
+func (s *downloadStats) addLyrics(status string) {
+	switch status {
+	case lyricsSynced:
+		s.lyricsSynced++
+	case lyricsPlain:
+		s.lyricsPlain++
+	case lyricsMissing:
+		s.lyricsMissing++
+	}
+}
+
+// hasLyrics reports whether lyrics were requested for a downloaded song.
+func (s *downloadStats) hasLyrics() bool {
+	return s.lyricsSynced+s.lyricsPlain+s.lyricsMissing > 0
 }
 
 type downloadResult struct {
-	success  bool
-	skipped  bool
-	path     string
+	success bool
+	skipped bool
+	path    string
+	quality string
+	// lyrics is the lyrics availability, empty when lyrics were not
+	// requested
+	lyrics   string
 	warnings []string
 	err      error
 }
 
+type pendingResult struct {
+	song   *deezer.Song
+	result downloadResult
+}
+
 type progressTracker struct {
 	logger       *logger.Logger
 	stats        *downloadStats
 	totalSongs   int
 	resourceType string
+	// events is set in JSON output mode, in which case no spinner is used
+	events *eventWriter
+
+	mu      sync.Mutex
+	spinner *spinner.Spinner
+	active  map[int]*deezer.Song
+	pending map[int]pendingResult
+	next    int
+	// done holds the IDs of the songs downloaded or already on disk
+	done map[string]bool
 }
 
-func newProgressTracker(logger *logger.Logger, totalSongs int, resourceType string) *progressTracker {
-	return &progressTracker{
+func newProgressTracker(logger *logger.Logger, totalSongs int, resourceType, output string) *progressTracker {
+	pt := &progressTracker{
 		logger:       logger,
 		stats:        &downloadStats{},
 		totalSongs:   totalSongs,
 		resourceType: resourceType,
+		active:       make(map[int]*deezer.Song),
+		pending:      make(map[int]pendingResult),
+		done:         make(map[string]bool),
+	}
+
+	if output == OutputJSON {
+		pt.events = newEventWriter(os.Stdout)
+	} else {
+		pt.spinner = spinner.New(spinner.CharSets[14], 100*time.Millisecond)
+		pt.spinner.Writer = os.Stdout
 	}
+
+	return pt
 }
 
-func (pt *progressTracker) startDownload(index int, song *deezer.Song) *spinner.Spinner {
-	songTitle := song.GetTitle()
-	trackProgress := fmt.Sprintf("[%d/%d]", index+1, pt.totalSongs)
+// printStart announces the resource before its songs are downloaded.
+func (pt *progressTracker) printStart(resource deezer.Resource, resourceID string) {
+	if pt.events != nil {
+		pt.events.emit(startEvent{
+			Event:      eventStart,
+			Type:       pt.resourceType,
+			ID:         resourceID,
+			Title:      resource.GetTitle(),
+			TotalSongs: pt.totalSongs,
+		})
+		return
+	}
+
+	if pt.resourceType != "track" {
+		fmt.Printf("%s\n\nStarting download...\n\n", resource)
+	}
+}
+
+// printWarning reports a warning that is not tied to a single song.
+func (pt *progressTracker) printWarning(format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+	pt.logger.Warnf("Warning: %s\n", msg)
+
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
 
-	sp := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
-	sp.Writer = os.Stdout
-	sp.Prefix = trackProgress + " "
-	sp.Suffix = fmt.Sprintf(" Downloading: %s - %s", song.Artist, songTitle)
-	sp.Start()
+	if pt.events != nil {
+		pt.events.emit(warningEvent{Event: eventWarning, Message: msg})
+		return
+	}
+
+	// The spinner of the songs in flight is drawn again below the warning
+	pt.stopSpinner()
+	fmt.Printf("Warning: %s\n", msg)
+	if len(pt.active) > 0 {
+		pt.startSpinner()
+	}
+}
+
+func (pt *progressTracker) startDownload(index int, song *deezer.Song) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
 
-	return sp
+	pt.active[index] = song
+	pt.updateSpinner()
+	pt.startSpinner()
+}
+
+// cancelDownload forgets an in-flight song without recording a result,
+// which is used when the whole run is being cancelled.
+func (pt *progressTracker) cancelDownload(index int) {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	delete(pt.active, index)
+	if len(pt.active) == 0 {
+		pt.stopSpinner()
+		return
+	}
+	pt.updateSpinner()
 }
 
+// handleResult records the result of a song and prints every result that
+// is ready in playlist order, so that [i/N] lines never appear out of order
+// even when songs finish concurrently.
 func (pt *progressTracker) handleResult(index int, song *deezer.Song, result downloadResult) {
-	trackProgress := fmt.Sprintf("[%d/%d]", index+1, pt.totalSongs)
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	delete(pt.active, index)
+	pt.pending[index] = pendingResult{song: song, result: result}
+	if result.success || result.skipped {
+		pt.done[song.ID] = true
+	}
+
+	if _, ok := pt.pending[pt.next]; !ok {
+		pt.updateSpinner()
+		return
+	}
+
+	pt.stopSpinner()
+	for {
+		p, ok := pt.pending[pt.next]
+		if !ok {
+			break
+		}
+		delete(pt.pending, pt.next)
+		pt.printResult(pt.next, p.song, p.result)
+		pt.next++
+	}
+
+	if len(pt.active) > 0 {
+		pt.updateSpinner()
+		pt.startSpinner()
+	}
+}
+
+func (pt *progressTracker) stop() {
+	pt.mu.Lock()
+	defer pt.mu.Unlock()
+
+	pt.stopSpinner()
+}
+
+func (pt *progressTracker) startSpinner() {
+	if pt.spinner != nil {
+		pt.spinner.Start()
+	}
+}
+
+func (pt *progressTracker) stopSpinner() {
+	if pt.spinner != nil {
+		pt.spinner.Stop()
+	}
+}
+
+func (pt *progressTracker) updateSpinner() {
+	if pt.spinner == nil {
+		return
+	}
+
+	indexes := make([]int, 0, len(pt.active))
+	for i := range pt.active {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	pt.spinner.Lock()
+	defer pt.spinner.Unlock()
+
+	if len(indexes) == 1 {
+		song := pt.active[indexes[0]]
+		pt.spinner.Prefix = pt.trackProgress(indexes[0]) + " "
+		pt.spinner.Suffix = fmt.Sprintf(" Downloading: %s - %s", song.Artist, song.GetTitle())
+		return
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, " Downloading %d songs...", len(indexes))
+	for _, i := range indexes {
+		song := pt.active[i]
+		fmt.Fprintf(&b, "\n    %s %s - %s", pt.trackProgress(i), song.Artist, song.GetTitle())
+	}
+	pt.spinner.Prefix = ""
+	pt.spinner.Suffix = b.String()
+}
+
+func (pt *progressTracker) trackProgress(index int) string {
+	return fmt.Sprintf("[%d/%d]", index+1, pt.totalSongs)
+}
+
+func (pt *progressTracker) printResult(index int, song *deezer.Song, result downloadResult) {
+	if pt.events != nil {
+		pt.recordResult(song, result)
+		pt.events.emit(newSongEvent(index, pt.totalSongs, song, result))
+		return
+	}
+
+	trackProgress := pt.trackProgress(index)
 	songTitle := song.GetTitle()
 
 	if result.skipped {
 		pt.stats.skipped++
 		fmt.Printf("%s ↷ Skipped: %s - %s\n    Already exists at: %s\n",
 			trackProgress, song.Artist, songTitle, result.path)
+		pt.printWarnings(result.warnings)
 		return
 	}
 
@@ -79,34 +289,68 @@ func (pt *progressTracker) handleResult(index int, song *deezer.Song, result dow
 	}
 
 	pt.stats.downloaded++
+	pt.stats.addLyrics(result.lyrics)
 	pt.logger.Infof("Downloaded %s - %s\n", song.Artist, songTitle)
 	fmt.Printf("%s %s Downloaded: %s - %s\n", trackProgress, symbol, song.Artist, songTitle)
 
-	for _, w := range result.warnings {
+	pt.printWarnings(result.warnings)
+}
+
+// printWarnings prints and logs the warnings of a song.
+func (pt *progressTracker) printWarnings(warnings []string) {
+	for _, w := range warnings {
 		pt.logger.Warnf("Warning: %s\n", w)
 		fmt.Printf("    Warning: %s\n", w)
 	}
 }
 
+// recordResult updates the stats and the log for a song result, without
+// printing anything to the terminal.
+func (pt *progressTracker) recordResult(song *deezer.Song, result downloadResult) {
+	switch {
+	case result.skipped:
+		pt.stats.skipped++
+		for _, w := range result.warnings {
+			pt.logger.Warnf("Warning: %s\n", w)
+		}
+	case result.err != nil:
+		pt.stats.failed++
+		pt.logger.Errorf("Failed to download %s - %s: %v\n", song.Artist, song.GetTitle(), result.err)
+	default:
+		pt.stats.downloaded++
+		pt.stats.addLyrics(result.lyrics)
+		pt.logger.Infof("Downloaded %s - %s\n", song.Artist, song.GetTitle())
+		for _, w := range result.warnings {
+			pt.logger.Warnf("Warning: %s\n", w)
+		}
+	}
+}
+
 func (pt *progressTracker) printSummary(resourceTitle, resourceID, outputDir string, elapsed time.Duration) {
 	if pt.stats.downloaded > 0 || pt.stats.failed > 0 {
 		pt.logger.Infof("Resource %s (%s): %d downloaded, %d skipped, %d failed\n",
 			resourceTitle, resourceID, pt.stats.downloaded, pt.stats.skipped, pt.stats.failed)
 	}
 
+	if pt.events != nil {
+		pt.events.emit(newSummaryEvent(pt.resourceType, resourceID, resourceTitle, outputDir, pt.stats, elapsed))
+		return
+	}
+
 	if pt.resourceType != "track" {
 		fmt.Printf(`
 ================== [ Summary ] ==================
 Downloaded:     %d
 Skipped:        %d
 Failed:         %d
-Elapsed time:   %s
+%sElapsed time:   %s
 Files saved to: %s
 =================================================
 `,
 			pt.stats.downloaded,
 			pt.stats.skipped,
 			pt.stats.failed,
+			pt.lyricsSummary(),
 			elapsed.Round(time.Second),
 			outputDir,
 		)
@@ -117,6 +361,17 @@ Files saved to: %s
 	}
 }
 
+// lyricsSummary returns the summary line of the lyrics availability, or an
+// empty string when no lyrics were requested.
+func (pt *progressTracker) lyricsSummary() string {
+	if !pt.stats.hasLyrics() {
+		return ""
+	}
+
+	return fmt.Sprintf("Lyrics:         %d synced, %d plain, %d missing\n",
+		pt.stats.lyricsSynced, pt.stats.lyricsPlain, pt.stats.lyricsMissing)
+}
+
 func (pt *progressTracker) showSupportMessage() {
 	if rand.Float64() < 0.1 {
 		fmt.Printf("\n💖 Enjoying GoDeez? Give us a ⭐ on GitHub: https://github.com/mathismqn/godeez\n")
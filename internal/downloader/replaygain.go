@@ -0,0 +1,91 @@
+package downloader
+
+import (
+	"context"
+
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/fileutil"
+	"github.com/mathismqn/godeez/internal/replaygain"
+	"github.com/mathismqn/godeez/internal/store"
+	"github.com/mathismqn/godeez/internal/tags"
+)
+
+// writeTrackReplayGain analyses a song on its own and writes its track
+// ReplayGain tags.
+func writeTrackReplayGain(path string) error {
+	track, err := replaygain.Analyze(path)
+	if err != nil {
+		return err
+	}
+
+	values, err := replaygain.TrackValues(track)
+	if err != nil {
+		return err
+	}
+
+	return tags.WriteReplayGain(path, values)
+}
+
+// applyAlbumReplayGain analyses the songs of an album together once it is
+// downloaded, and writes their track and album ReplayGain tags. Songs
+// downloaded by earlier runs are included, so that the album gain covers
+// the whole album, while songs that failed to download are left out.
+// Failures are reported as warnings.
+func (c *Client) applyAlbumReplayGain(ctx context.Context, opts Options, resource deezer.Resource, progress *progressTracker) {
+	album, ok := resource.(*deezer.Album)
+	if !opts.ReplayGain || !ok || progress.stats.downloaded == 0 {
+		return
+	}
+
+	var infos []*store.DownloadInfo
+	var tracks []*replaygain.Track
+	for _, song := range album.GetSongs() {
+		if ctx.Err() != nil {
+			return
+		}
+
+		info, err := store.GetDownloadInfo(c.appConfig.Profile, song.ID)
+		if err != nil || !fileutil.FileExists(info.Path) {
+			continue
+		}
+
+		track, err := replaygain.Analyze(info.Path)
+		if err != nil {
+			progress.printWarning("failed to analyse %s: %v", info.Path, err)
+			continue
+		}
+		infos = append(infos, info)
+		tracks = append(tracks, track)
+	}
+	if len(tracks) == 0 {
+		return
+	}
+
+	values, err := replaygain.AlbumValues(tracks)
+	if err != nil {
+		progress.printWarning("failed to compute album gain: %v", err)
+		return
+	}
+
+	for i, info := range infos {
+		if err := tags.WriteReplayGain(info.Path, values[i]); err != nil {
+			progress.printWarning("failed to write ReplayGain tags to %s: %v", info.Path, err)
+			continue
+		}
+		if err := updateHash(info); err != nil {
+			progress.printWarning("failed to save download info of %s: %v", info.Path, err)
+		}
+	}
+}
+
+// updateHash records the hash of a file whose tags were rewritten, so that
+// it is still found when moved.
+func updateHash(info *store.DownloadInfo) error {
+	hash, err := fileutil.GetFileHash(info.Path)
+	if err != nil {
+		return err
+	}
+	info.Hash = hash
+
+	return info.Save()
+}
@@ -0,0 +1,394 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/deezer"
+	"github.com/mathismqn/godeez/internal/fileutil"
+	"github.com/mathismqn/godeez/internal/replaygain"
+	"github.com/mathismqn/godeez/internal/store"
+	"github.com/mathismqn/godeez/internal/tags"
+)
+
+// Groups of tags rewritten by Retag.
+const (
+	// RetagBasic is the tags of the song and its album: title, artists,
+	// album, track and disc numbers, dates, label, copyright and ISRC
+	RetagBasic      = "basic"
+	RetagGenre      = "genre"
+	RetagBPM        = "bpm"
+	RetagCover      = "cover"
+	RetagLyrics     = "lyrics"
+	RetagReplayGain = "replaygain"
+)
+
+// RetagGroups are the groups of tags Retag can rewrite.
+var RetagGroups = []string{RetagBasic, RetagGenre, RetagBPM, RetagCover, RetagLyrics, RetagReplayGain}
+
+// DefaultRetagGroups are the groups rewritten when none is selected.
+var DefaultRetagGroups = []string{RetagBasic, RetagGenre, RetagBPM, RetagCover}
+
+// retagValueWidth is the number of characters of a tag value shown in the
+// changes, longer values such as lyrics being cut.
+const retagValueWidth = 60
+
+// RetagOptions selects the tags rewritten by Retag.
+type RetagOptions struct {
+	// Only lists the groups of tags to rewrite, DefaultRetagGroups when
+	// empty
+	Only []string
+	// Lyrics and ReplayGain add their group to the others
+	Lyrics bool
+	// ReplayGain analyses the loudness of the songs and rewrites their
+	// ReplayGain tags, songs tagged with the same album and album artist
+	// being analysed together for the album values
+	ReplayGain bool
+	// DryRun prints the changes without writing them
+	DryRun bool
+}
+
+func (o *RetagOptions) Validate() error {
+	for _, group := range o.Only {
+		if !slices.Contains(RetagGroups, group) {
+			return fmt.Errorf("invalid tag group: %s (available: %s)", group, strings.Join(RetagGroups, ", "))
+		}
+	}
+
+	return nil
+}
+
+// groups returns the set of groups to rewrite.
+func (o *RetagOptions) groups() map[string]bool {
+	only := o.Only
+	if len(only) == 0 {
+		only = DefaultRetagGroups
+	}
+
+	groups := make(map[string]bool)
+	for _, group := range only {
+		groups[group] = true
+	}
+	if o.Lyrics {
+		groups[RetagLyrics] = true
+	}
+	if o.ReplayGain {
+		groups[RetagReplayGain] = true
+	}
+
+	return groups
+}
+
+// fetchesMetadata reports whether groups need the metadata of the songs.
+func fetchesMetadata(groups map[string]bool) bool {
+	return groups[RetagBasic] || groups[RetagGenre] || groups[RetagBPM] || groups[RetagCover] || groups[RetagLyrics]
+}
+
+// retagStats counts the files handled by Retag. A file changed by one pass
+// and failed by another counts as failed.
+type retagStats struct {
+	changed map[string]bool
+	failed  map[string]bool
+	missing int
+}
+
+func (s *retagStats) fail(path string) {
+	s.failed[path] = true
+}
+
+func (s *retagStats) change(path string, changes []tags.Change) {
+	if len(changes) > 0 {
+		s.changed[path] = true
+	}
+}
+
+// Retag rewrites the tags of the songs downloaded with the profile of
+// appConfig, as recorded in the store, with metadata fetched again from
+// Deezer.
+func Retag(ctx context.Context, appConfig *config.Config, opts RetagOptions) error {
+	infos, err := store.ListDownloadInfo(appConfig.Profile)
+	if err != nil {
+		return fmt.Errorf("failed to list downloaded songs: %w", err)
+	}
+
+	startTime := time.Now()
+	stats := &retagStats{changed: make(map[string]bool), failed: make(map[string]bool)}
+	var existing []*store.DownloadInfo
+	for _, info := range infos {
+		if fileutil.FileExists(info.Path) {
+			existing = append(existing, info)
+		} else {
+			stats.missing++
+		}
+	}
+	if len(existing) == 0 {
+		fmt.Println("No downloaded songs to retag")
+		return nil
+	}
+
+	groups := opts.groups()
+	if fetchesMetadata(groups) {
+		c := New(appConfig, "track")
+		// No media is downloaded, so the quality of the account does not matter
+		if err := c.initDeezerClient(ctx, Options{Retries: DefaultRetries, RetryBackoff: DefaultRetryBackoff}); err != nil {
+			return err
+		}
+
+		for i, info := range existing {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.retagSong(ctx, info, fmt.Sprintf("[%d/%d]", i+1, len(existing)), groups, opts.DryRun, stats)
+		}
+	}
+
+	if groups[RetagReplayGain] {
+		retagReplayGain(ctx, existing, opts.DryRun, stats)
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if !opts.DryRun {
+		for _, info := range existing {
+			if !stats.changed[info.Path] {
+				continue
+			}
+			if err := updateHash(info); err != nil {
+				fmt.Printf("Warning: failed to save download info of %s: %v\n", info.Path, err)
+			}
+		}
+	}
+
+	changedLabel := "Changed:      "
+	if opts.DryRun {
+		changedLabel = "Would change: "
+	}
+	changed := 0
+	for path := range stats.changed {
+		if !stats.failed[path] {
+			changed++
+		}
+	}
+
+	fmt.Printf(`
+================== [ Summary ] ==================
+%s  %d
+Unchanged:      %d
+Missing:        %d
+Failed:         %d
+Elapsed time:   %s
+=================================================
+`,
+		changedLabel,
+		changed,
+		len(existing)-changed-len(stats.failed),
+		stats.missing,
+		len(stats.failed),
+		time.Since(startTime).Round(time.Second),
+	)
+
+	return nil
+}
+
+// retagSong fetches the metadata of a downloaded song and rewrites the tags
+// of groups, printing the changes.
+func (c *Client) retagSong(ctx context.Context, info *store.DownloadInfo, progress string, groups map[string]bool, dryRun bool, stats *retagStats) {
+	resource, err := c.fetchResource(ctx, info.SongID, Options{})
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			fmt.Printf("%s ✖ Failed: %s\n    Error: %v\n", progress, info.Path, err)
+			stats.fail(info.Path)
+		}
+		return
+	}
+	song := resource.GetSongs()[0]
+
+	var warnings []string
+	var album *deezer.Album
+	var songTags *deezer.Song
+	if groups[RetagBasic] {
+		songTags = song
+		album, err = c.songAlbum(ctx, nil, song)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to fetch album: %v", err))
+		}
+	}
+
+	metadataFetcher := newMetadataFetcher(c.deezerClient.Session.HttpClient, c.deezerClient.Session.Endpoints)
+	metadataResult := metadataFetcher.fetch(ctx, song, Options{BPM: groups[RetagBPM], Genre: groups[RetagGenre]})
+	warnings = append(warnings, metadataResult.warnings...)
+
+	var cover []byte
+	if groups[RetagCover] {
+		cover, err = c.deezerClient.FetchCoverImage(ctx, song)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to fetch cover image: %v", err))
+		}
+	}
+
+	var lyrics *deezer.Lyrics
+	if groups[RetagLyrics] {
+		lyrics, _, err = c.fetchLyrics(ctx, song)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to fetch lyrics: %v", err))
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	bpmKey := metadataResult.bpmKey
+	changes, err := tags.UpdateTags(album, songTags, cover, info.Path, bpmKey.BPM, bpmKey.Key, metadataResult.genre, lyrics, dryRun)
+	if err != nil {
+		fmt.Printf("%s ✖ Failed: %s - %s\n    Error: %v\n", progress, song.Artist, song.GetTitle(), err)
+		stats.fail(info.Path)
+		return
+	}
+	if !dryRun && lyrics != nil && len(lyrics.Synced) > 0 {
+		if err := tags.WriteLRC(info.Path, song, lyrics); err != nil {
+			warnings = append(warnings, fmt.Sprintf("failed to write lyrics file: %v", err))
+		}
+	}
+	stats.change(info.Path, changes)
+
+	status := "✔ Retagged"
+	switch {
+	case len(changes) == 0:
+		status = "- Unchanged"
+	case len(warnings) > 0:
+		status = "⚠ Retagged"
+	}
+	fmt.Printf("%s %s: %s - %s\n", progress, status, song.Artist, song.GetTitle())
+	printChanges(changes)
+	for _, w := range warnings {
+		fmt.Printf("    Warning: %s\n", w)
+	}
+}
+
+// printChanges prints the changed tags of a file, one per line.
+func printChanges(changes []tags.Change) {
+	for _, change := range changes {
+		fmt.Printf("    %s: %s -> %s\n", change.Name, quoteValue(change.Old), quoteValue(change.New))
+	}
+}
+
+// quoteValue quotes a tag value for printChanges, cutting long values.
+func quoteValue(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	if utf8.RuneCountInString(value) > retagValueWidth {
+		value = string([]rune(value)[:retagValueWidth]) + "…"
+	}
+
+	return fmt.Sprintf("%q", value)
+}
+
+// replayGainGroup is a set of songs analysed together, either the songs of
+// an album or a song without an album.
+type replayGainGroup struct {
+	album bool
+	infos []*store.DownloadInfo
+}
+
+// retagReplayGain writes the ReplayGain tags of songs, with album values
+// for the songs tagged with an album.
+func retagReplayGain(ctx context.Context, infos []*store.DownloadInfo, dryRun bool, stats *retagStats) {
+	var groups []replayGainGroup
+	albumIndex := make(map[string]int)
+	for _, info := range infos {
+		fields, err := tags.Read(info.Path)
+		if err != nil {
+			fmt.Printf("✖ Failed to read tags of %s: %v\n", info.Path, err)
+			stats.fail(info.Path)
+			continue
+		}
+
+		album := fields["ALBUM"]
+		if album == "" {
+			groups = append(groups, replayGainGroup{infos: []*store.DownloadInfo{info}})
+			continue
+		}
+		key := fields["ALBUMARTIST"] + "\x00" + album
+		if i, ok := albumIndex[key]; ok {
+			groups[i].infos = append(groups[i].infos, info)
+		} else {
+			albumIndex[key] = len(groups)
+			groups = append(groups, replayGainGroup{album: true, infos: []*store.DownloadInfo{info}})
+		}
+	}
+
+	for _, group := range groups {
+		if ctx.Err() != nil {
+			return
+		}
+
+		var analysed []*store.DownloadInfo
+		var tracks []*replaygain.Track
+		for _, info := range group.infos {
+			track, err := replaygain.Analyze(info.Path)
+			if err != nil {
+				fmt.Printf("✖ Failed to analyse %s: %v\n", info.Path, err)
+				stats.fail(info.Path)
+				continue
+			}
+			analysed = append(analysed, info)
+			tracks = append(tracks, track)
+		}
+		if len(tracks) == 0 {
+			continue
+		}
+
+		values, err := replayGainValues(tracks, group.album)
+		if err != nil {
+			for _, info := range analysed {
+				fmt.Printf("✖ Failed to compute ReplayGain of %s: %v\n", info.Path, err)
+				stats.fail(info.Path)
+			}
+			continue
+		}
+
+		for i, info := range analysed {
+			changes, err := tags.UpdateReplayGain(info.Path, values[i], dryRun)
+			if err != nil {
+				fmt.Printf("✖ Failed to write ReplayGain tags to %s: %v\n", info.Path, err)
+				stats.fail(info.Path)
+				continue
+			}
+			stats.change(info.Path, changes)
+
+			status := "✔ ReplayGain"
+			if len(changes) == 0 {
+				status = "- ReplayGain unchanged"
+			}
+			fmt.Printf("%s: %s\n", status, info.Path)
+			printChanges(changes)
+		}
+	}
+}
+
+// replayGainValues returns the ReplayGain tags of tracks, with album values
+// when they are the songs of an album.
+func replayGainValues(tracks []*replaygain.Track, album bool) ([]replaygain.Values, error) {
+	if album {
+		return replaygain.AlbumValues(tracks)
+	}
+
+	values := make([]replaygain.Values, len(tracks))
+	for i, track := range tracks {
+		v, err := replaygain.TrackValues(track)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
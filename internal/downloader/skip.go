@@ -22,13 +22,13 @@ func IsSkipError(err error) (string, bool) {
 	return "", false
 }
 
-func (c *Client) shouldSkipDownload(ctx context.Context, songID, mediaFormat string) (string, bool) {
-	if existing, err := store.GetDownloadInfo(songID); err == nil && existing.Quality == mediaFormat {
+func (c *Client) shouldSkipDownload(ctx context.Context, songID, mediaFormat, root string) (string, bool) {
+	if existing, err := store.GetDownloadInfo(c.appConfig.Profile, songID); err == nil && existing.Quality == mediaFormat {
 		if fileutil.FileExists(existing.Path) {
 			return existing.Path, true
 		}
 		if existing.Hash != "" {
-			if err := c.initHashIndex(ctx); err == nil {
+			if err := c.initHashIndex(ctx, root); err == nil {
 				if foundPath, ok := c.hashIndex.Find(existing.Hash); ok {
 					existing.Path = foundPath
 					_ = existing.Save()
@@ -14,7 +14,12 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
-type BPMProvider struct{}
+// DefaultSongBPMURL is the base URL used when BPMProvider.BaseURL is unset.
+const DefaultSongBPMURL = "https://songbpm.com"
+
+type BPMProvider struct {
+	BaseURL string
+}
 
 type BPMKey struct {
 	BPM string
@@ -36,7 +41,10 @@ func (p BPMProvider) Fetch(ctx context.Context, httpClient *http.Client, artist,
 }
 
 func (p BPMProvider) findSongURL(ctx context.Context, httpClient *http.Client, artist, title, duration string) (string, error) {
-	rootUrl := "https://songbpm.com"
+	rootUrl := p.BaseURL
+	if rootUrl == "" {
+		rootUrl = DefaultSongBPMURL
+	}
 	reqUrl := rootUrl + "/searches"
 
 	values := neturl.Values{}
@@ -47,7 +55,7 @@ func (p BPMProvider) findSongURL(ctx context.Context, httpClient *http.Client, a
 		return "", err
 	}
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Origin", "https://songbpm.com")
+	req.Header.Set("Origin", rootUrl)
 
 	resp, err := httpClient.Do(req)
 	if err != nil {
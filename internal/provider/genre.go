@@ -9,10 +9,20 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
-type GenreProvider struct{}
+// DefaultLastFMURL is the base URL used when GenreProvider.BaseURL is unset.
+const DefaultLastFMURL = "https://www.last.fm"
+
+type GenreProvider struct {
+	BaseURL string
+}
 
 func (p GenreProvider) Fetch(ctx context.Context, httpClient *http.Client, artist, title string) (string, error) {
-	reqUrl := fmt.Sprintf("https://www.last.fm/music/%s/%s/+tags", artist, title)
+	rootUrl := p.BaseURL
+	if rootUrl == "" {
+		rootUrl = DefaultLastFMURL
+	}
+
+	reqUrl := fmt.Sprintf("%s/music/%s/%s/+tags", rootUrl, artist, title)
 	doc, err := p.fetchPage(ctx, httpClient, reqUrl)
 	if err != nil {
 		return "", err
@@ -0,0 +1,97 @@
+package replaygain
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hajimehoshi/go-mp3"
+	"github.com/mewkiz/flac"
+)
+
+// Analyze decodes an MP3 or FLAC file and measures its loudness.
+func Analyze(path string) (*Track, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".flac":
+		return analyzeFLAC(path)
+	case ".mp3":
+		return analyzeMP3(path)
+	default:
+		return nil, fmt.Errorf("unsupported file format: %s", filepath.Ext(path))
+	}
+}
+
+func analyzeFLAC(path string) (*Track, error) {
+	stream, err := flac.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open FLAC file: %w", err)
+	}
+	defer stream.Close()
+
+	info := stream.Info
+	channels := int(info.NChannels)
+	m := newMeter(int(info.SampleRate), channels)
+	scale := float64(int64(1) << (info.BitsPerSample - 1))
+	samples := make([]float64, channels)
+
+	for {
+		frame, err := stream.ParseNext()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode FLAC file: %w", err)
+		}
+
+		for i := range int(frame.BlockSize) {
+			for ch, subframe := range frame.Subframes {
+				samples[ch] = float64(subframe.Samples[i]) / scale
+			}
+			m.add(samples)
+		}
+	}
+
+	return &Track{blocks: m.blocks(), Peak: m.peak}, nil
+}
+
+func analyzeMP3(path string) (*Track, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	d, err := mp3.NewDecoder(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MP3 file: %w", err)
+	}
+
+	// The decoder always outputs interleaved 16-bit little-endian stereo
+	const channels, frameSize = 2, 4
+	m := newMeter(d.SampleRate(), channels)
+	samples := make([]float64, channels)
+	buf := make([]byte, 1024*frameSize)
+
+	for {
+		n, err := io.ReadFull(d, buf)
+		for i := 0; i+frameSize <= n; i += frameSize {
+			for ch := range channels {
+				sample := int16(binary.LittleEndian.Uint16(buf[i+2*ch:]))
+				samples[ch] = float64(sample) / 32768
+			}
+			m.add(samples)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode MP3 file: %w", err)
+		}
+	}
+
+	return &Track{blocks: m.blocks(), Peak: m.peak}, nil
+}
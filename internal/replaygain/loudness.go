@@ -0,0 +1,193 @@
+package replaygain
+
+import (
+	"math"
+)
+
+// Gating of ITU-R BS.1770-4, on which EBU R128 and ReplayGain 2.0 rely:
+// the loudness is measured over blocks of 400ms overlapping by 75%, and
+// blocks quieter than an absolute and a relative threshold are ignored.
+const (
+	blockDuration   = 0.4
+	blockSteps      = 4
+	absoluteGate    = -70.0
+	relativeGate    = -10.0
+	loudnessOffset  = -0.691
+	surroundWeight  = 1.41
+	lfeChannelIndex = 3
+)
+
+// biquad is a second order IIR filter in direct form I.
+type biquad struct {
+	b0, b1, b2 float64
+	a1, a2     float64
+	x1, x2     float64
+	y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+
+	return y
+}
+
+// kWeighting returns the two stages of the K-weighting filter of BS.1770
+// for a sample rate: a high shelf modelling the head, then a high-pass
+// filter. The coefficients are derived for any sample rate from the
+// analog prototypes, as libebur128 does.
+func kWeighting(sampleRate float64) (biquad, biquad) {
+	// High shelf
+	f0, gain, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / sampleRate)
+	vh := math.Pow(10, gain/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	shelf := biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// High-pass
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / sampleRate)
+	a0 = 1 + k/q + k*k
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return shelf, highPass
+}
+
+// meter measures the loudness of a stream of samples. Samples are fed one
+// frame at a time, a frame holding one sample per channel between -1 and 1.
+type meter struct {
+	shelves   []biquad
+	highPass  []biquad
+	weights   []float64
+	stepSize  int
+	stepCount int
+	stepSum   float64
+	// steps holds the weighted energy of each 100ms step, four of which
+	// make a gating block
+	steps []float64
+	peak  float64
+}
+
+func newMeter(sampleRate, channels int) *meter {
+	m := &meter{
+		shelves:  make([]biquad, channels),
+		highPass: make([]biquad, channels),
+		weights:  make([]float64, channels),
+		stepSize: int(math.Round(float64(sampleRate) * blockDuration / blockSteps)),
+	}
+	for ch := range channels {
+		m.shelves[ch], m.highPass[ch] = kWeighting(float64(sampleRate))
+		m.weights[ch] = channelWeight(ch, channels)
+	}
+
+	return m
+}
+
+// channelWeight returns the weight of a channel in the WAVE channel order
+// (L, R, C, LFE, Ls, Rs): surround channels are louder to the listener and
+// the LFE channel is ignored.
+func channelWeight(ch, channels int) float64 {
+	switch {
+	case channels < 6:
+		return 1
+	case ch == lfeChannelIndex:
+		return 0
+	case ch > lfeChannelIndex:
+		return surroundWeight
+	default:
+		return 1
+	}
+}
+
+func (m *meter) add(frame []float64) {
+	for ch, x := range frame {
+		m.peak = max(m.peak, math.Abs(x))
+		y := m.highPass[ch].process(m.shelves[ch].process(x))
+		m.stepSum += m.weights[ch] * y * y
+	}
+
+	m.stepCount++
+	if m.stepCount == m.stepSize {
+		m.steps = append(m.steps, m.stepSum)
+		m.stepSum = 0
+		m.stepCount = 0
+	}
+}
+
+// blocks returns the mean square energy of each gating block. The last
+// step is dropped when incomplete, as BS.1770 does with the last block.
+func (m *meter) blocks() []float64 {
+	if len(m.steps) < blockSteps {
+		return nil
+	}
+
+	blocks := make([]float64, 0, len(m.steps)-blockSteps+1)
+	for i := blockSteps; i <= len(m.steps); i++ {
+		var sum float64
+		for _, step := range m.steps[i-blockSteps : i] {
+			sum += step
+		}
+		blocks = append(blocks, sum/float64(blockSteps*m.stepSize))
+	}
+
+	return blocks
+}
+
+// integratedLoudness returns the gated loudness of blocks in LUFS, and
+// false when every block is below the absolute gate.
+func integratedLoudness(blocks []float64) (float64, bool) {
+	absolute := energy(absoluteGate)
+	mean, ok := gatedMean(blocks, absolute)
+	if !ok {
+		return 0, false
+	}
+
+	relative := max(absolute, mean*math.Pow(10, relativeGate/10))
+	mean, ok = gatedMean(blocks, relative)
+	if !ok {
+		return 0, false
+	}
+
+	return loudness(mean), true
+}
+
+// gatedMean returns the mean of the blocks louder than threshold.
+func gatedMean(blocks []float64, threshold float64) (float64, bool) {
+	var sum float64
+	var n int
+	for _, block := range blocks {
+		if block > threshold {
+			sum += block
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, false
+	}
+
+	return sum / float64(n), true
+}
+
+// loudness converts a mean square energy to LUFS.
+func loudness(energy float64) float64 {
+	return loudnessOffset + 10*math.Log10(energy)
+}
+
+// energy converts a loudness in LUFS to a mean square energy.
+func energy(loudness float64) float64 {
+	return math.Pow(10, (loudness-loudnessOffset)/10)
+}
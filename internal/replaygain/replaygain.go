@@ -0,0 +1,105 @@
+// Package replaygain computes ReplayGain 2.0 values from the loudness of
+// audio files, measured as specified by EBU R128 and ITU-R BS.1770.
+package replaygain
+
+import (
+	"errors"
+)
+
+// Reference is the loudness in LUFS ReplayGain 2.0 brings tracks to.
+const Reference = -18.0
+
+// ErrSilent is returned when a track or an album is too quiet for its
+// loudness to be measured.
+var ErrSilent = errors.New("audio is silent")
+
+// Track is the loudness analysis of a track.
+type Track struct {
+	// blocks is the energy of each gating block, kept to measure the
+	// loudness of the album
+	blocks []float64
+	// Peak is the highest absolute sample value, 1 being full scale
+	Peak float64
+}
+
+// Loudness returns the integrated loudness of the track in LUFS.
+func (t *Track) Loudness() (float64, error) {
+	loudness, ok := integratedLoudness(t.blocks)
+	if !ok {
+		return 0, ErrSilent
+	}
+
+	return loudness, nil
+}
+
+// Gain returns the gain in dB to apply to the track to bring it to the
+// reference loudness.
+func (t *Track) Gain() (float64, error) {
+	loudness, err := t.Loudness()
+	if err != nil {
+		return 0, err
+	}
+
+	return Reference - loudness, nil
+}
+
+// Album returns the analysis of an album made of tracks: its loudness is
+// measured over the blocks of all the tracks, not averaged, and its peak is
+// the highest of the tracks.
+func Album(tracks []*Track) *Track {
+	album := &Track{}
+	for _, track := range tracks {
+		album.blocks = append(album.blocks, track.blocks...)
+		album.Peak = max(album.Peak, track.Peak)
+	}
+
+	return album
+}
+
+// Values are the ReplayGain tags of a track. The album values are only
+// written when HasAlbum is set.
+type Values struct {
+	TrackGain float64
+	TrackPeak float64
+	HasAlbum  bool
+	AlbumGain float64
+	AlbumPeak float64
+}
+
+// TrackValues returns the tags of a track analysed on its own.
+func TrackValues(track *Track) (Values, error) {
+	gain, err := track.Gain()
+	if err != nil {
+		return Values{}, err
+	}
+
+	return Values{TrackGain: gain, TrackPeak: track.Peak}, nil
+}
+
+// AlbumValues returns the tags of each track of an album, in the order of
+// tracks.
+func AlbumValues(tracks []*Track) ([]Values, error) {
+	album := Album(tracks)
+	albumGain, err := album.Gain()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]Values, len(tracks))
+	for i, track := range tracks {
+		// A silent track of an audible album only gets the album values
+		gain, err := track.Gain()
+		if err != nil {
+			gain = albumGain
+		}
+		values[i] = Values{
+			TrackGain: gain,
+			TrackPeak: track.Peak,
+			HasAlbum:  true,
+			AlbumGain: albumGain,
+			AlbumPeak: album.Peak,
+		}
+	}
+
+	return values, nil
+}
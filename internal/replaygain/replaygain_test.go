@@ -0,0 +1,131 @@
+package replaygain_test
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mathismqn/godeez/internal/deezertest"
+	"github.com/mathismqn/godeez/internal/replaygain"
+)
+
+// minus20dBFS is the amplitude of a sine peaking at -20 dBFS.
+var minus20dBFS = math.Pow(10, -20.0/20)
+
+// analyzeFLAC writes a FLAC file and analyses it.
+func analyzeFLAC(t *testing.T, data []byte) *replaygain.Track {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "song.flac")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	track, err := replaygain.Analyze(path)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	return track
+}
+
+func TestLoudnessReference(t *testing.T) {
+	// EBU Tech 3341: a 997 Hz sine at -20 dBFS on one channel measures
+	// -23.0 LUFS, and the same sine on two channels 3 LU more
+	tests := []struct {
+		name     string
+		channels int
+		want     float64
+	}{
+		{"mono", 1, -23.0},
+		{"stereo", 2, -20.0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			track := analyzeFLAC(t, deezertest.ToneFLACFormat(10, 997, minus20dBFS, tt.channels, 16))
+
+			loudness, err := track.Loudness()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(loudness-tt.want) > 0.1 {
+				t.Errorf("loudness = %.2f LUFS, want %.1f ±0.1", loudness, tt.want)
+			}
+			if math.Abs(track.Peak-minus20dBFS) > 0.001 {
+				t.Errorf("peak = %.4f, want %.4f", track.Peak, minus20dBFS)
+			}
+
+			gain, err := track.Gain()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(gain-(replaygain.Reference-tt.want)) > 0.1 {
+				t.Errorf("gain = %.2f dB, want %.1f ±0.1", gain, replaygain.Reference-tt.want)
+			}
+		})
+	}
+}
+
+func TestSilence(t *testing.T) {
+	track := analyzeFLAC(t, deezertest.ToneFLAC(5, 997, 0))
+
+	if _, err := track.Loudness(); !errors.Is(err, replaygain.ErrSilent) {
+		t.Errorf("Loudness error = %v, want ErrSilent", err)
+	}
+	if _, err := replaygain.TrackValues(track); !errors.Is(err, replaygain.ErrSilent) {
+		t.Errorf("TrackValues error = %v, want ErrSilent", err)
+	}
+	if _, err := replaygain.AlbumValues([]*replaygain.Track{track, track}); !errors.Is(err, replaygain.ErrSilent) {
+		t.Errorf("AlbumValues error = %v, want ErrSilent", err)
+	}
+}
+
+func TestAlbumOfIdenticalTracks(t *testing.T) {
+	track := analyzeFLAC(t, deezertest.ToneFLAC(5, 997, minus20dBFS))
+
+	want, err := replaygain.TrackValues(track)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values, err := replaygain.AlbumValues([]*replaygain.Track{track, track})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, v := range values {
+		if !v.HasAlbum {
+			t.Errorf("track %d has no album values", i)
+		}
+		if v.TrackGain != want.TrackGain || v.TrackPeak != want.TrackPeak {
+			t.Errorf("track %d values = %+v, want track gain %v and peak %v", i, v, want.TrackGain, want.TrackPeak)
+		}
+		if math.Abs(v.AlbumGain-want.TrackGain) > 1e-9 || v.AlbumPeak != want.TrackPeak {
+			t.Errorf("track %d album gain %v and peak %v, want %v and %v", i, v.AlbumGain, v.AlbumPeak, want.TrackGain, want.TrackPeak)
+		}
+	}
+}
+
+func TestBitDepth(t *testing.T) {
+	// Samples are scaled to full scale whatever their size
+	tracks := make(map[int]*replaygain.Track)
+	for _, bits := range []int{16, 24} {
+		tracks[bits] = analyzeFLAC(t, deezertest.ToneFLACFormat(5, 997, minus20dBFS, 2, bits))
+	}
+
+	loudness16, err := tracks[16].Loudness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	loudness24, err := tracks[24].Loudness()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(loudness16-loudness24) > 0.01 {
+		t.Errorf("loudness = %.3f LUFS at 16 bits and %.3f LUFS at 24 bits", loudness16, loudness24)
+	}
+	if math.Abs(tracks[16].Peak-tracks[24].Peak) > 0.001 {
+		t.Errorf("peak = %.5f at 16 bits and %.5f at 24 bits", tracks[16].Peak, tracks[24].Peak)
+	}
+}
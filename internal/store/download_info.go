@@ -3,36 +3,79 @@ package store
 import (
 	"encoding/json"
 	"fmt"
+	"path/filepath"
+	"slices"
 	"time"
-
-	"go.etcd.io/bbolt"
 )
 
 type DownloadInfo struct {
-	SongID     string    `json:"song_id"`
-	Quality    string    `json:"quality"`
-	Path       string    `json:"path"`
-	Hash       string    `json:"hash"`
+	SongID  string `json:"song_id"`
+	Quality string `json:"quality"`
+	Path    string `json:"path"`
+	Hash    string `json:"hash"`
+	Source  string `json:"source"`
+	// Sources are all the resources the song was downloaded or found on
+	// disk for. Records saved before they were tracked only have Source.
+	Sources []string `json:"sources,omitempty"`
+	// Profile is the config profile the song was downloaded with, empty
+	// when no profile was used
+	Profile    string    `json:"profile,omitempty"`
 	Downloaded time.Time `json:"downloaded_at"`
 }
 
 var trackBucket = []byte("tracks")
 
-func GetDownloadInfo(songID string) (*DownloadInfo, error) {
-	var info DownloadInfo
+// Source identifies the resource a song was downloaded for, e.g.
+// "playlist/123".
+func Source(resourceType, resourceID string) string {
+	return resourceType + "/" + resourceID
+}
 
-	if err := db.View(func(tx *bbolt.Tx) error {
-		b := tx.Bucket(trackBucket)
-		if b == nil {
-			return fmt.Errorf("bucket not found")
-		}
+// Owners returns the resources the song was downloaded or found on disk for.
+func (d *DownloadInfo) Owners() []string {
+	if len(d.Sources) == 0 && d.Source != "" {
+		return []string{d.Source}
+	}
 
-		data := b.Get([]byte(songID))
-		if data == nil {
-			return fmt.Errorf("not found")
-		}
-		return json.Unmarshal(data, &info)
-	}); err != nil {
+	return d.Sources
+}
+
+// AddSource records that the song belongs to source too. It reports whether
+// source was new.
+func (d *DownloadInfo) AddSource(source string) bool {
+	owners := d.Owners()
+	if slices.Contains(owners, source) {
+		return false
+	}
+	d.Sources = append(slices.Clip(owners), source)
+
+	return true
+}
+
+// profileKey returns the key of a record of a profile, such as a song it
+// downloaded or a playlist it watches. Each profile keeps its own records,
+// so that profiles with different output directories do not skip each
+// other's downloads or overwrite each other's watched playlists. Records
+// made without a profile are keyed by ID alone.
+func profileKey(profile, id string) []byte {
+	if profile == "" {
+		return []byte(id)
+	}
+
+	return []byte(profile + "/" + id)
+}
+
+func GetDownloadInfo(profile, songID string) (*DownloadInfo, error) {
+	data, err := db.get(trackBucket, profileKey(profile, songID))
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("not found")
+	}
+
+	var info DownloadInfo
+	if err := json.Unmarshal(data, &info); err != nil {
 		return nil, err
 	}
 
@@ -40,17 +83,140 @@ func GetDownloadInfo(songID string) (*DownloadInfo, error) {
 }
 
 func (d *DownloadInfo) Save() error {
-	return db.Update(func(tx *bbolt.Tx) error {
-		b, err := tx.CreateBucketIfNotExists(trackBucket)
+	data, err := json.Marshal(d)
+	if err != nil {
+		return err
+	}
+
+	return db.put(trackBucket, profileKey(d.Profile, d.SongID), data)
+}
+
+// AddDownloadSource records that a song downloaded with a profile belongs
+// to source too, such as a playlist it was skipped for because it was
+// already on disk.
+func AddDownloadSource(profile, songID, source string) error {
+	key := profileKey(profile, songID)
+	for {
+		old, err := db.get(trackBucket, key)
 		if err != nil {
-			return fmt.Errorf("failed to create bucket: %w", err)
+			return err
+		}
+		if old == nil {
+			return fmt.Errorf("not found")
 		}
 
-		data, err := json.Marshal(d)
+		var info DownloadInfo
+		if err := json.Unmarshal(old, &info); err != nil {
+			return err
+		}
+		if !info.AddSource(source) {
+			return nil
+		}
+		data, err := json.Marshal(&info)
 		if err != nil {
 			return err
 		}
 
-		return b.Put([]byte(d.SongID), data)
+		if ok, err := swap(trackBucket, key, old, data); ok || err != nil {
+			return err
+		}
+	}
+}
+
+func DeleteDownloadInfo(profile, songID string) error {
+	return db.delete(trackBucket, profileKey(profile, songID))
+}
+
+// ListDownloadInfo returns the songs downloaded with a profile, the songs
+// downloaded without a profile when profile is empty.
+func ListDownloadInfo(profile string) ([]*DownloadInfo, error) {
+	return listDownloadInfo(func(info *DownloadInfo) bool {
+		return info.Profile == profile
 	})
 }
+
+// DownloadInfoByPath returns the songs downloaded with every profile,
+// grouped by the file they were saved to. Profiles sharing an output
+// directory share the files of the songs they both downloaded.
+func DownloadInfoByPath() (map[string][]*DownloadInfo, error) {
+	infos, err := listDownloadInfo(func(*DownloadInfo) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+
+	byPath := make(map[string][]*DownloadInfo)
+	for _, info := range infos {
+		path := filepath.Clean(info.Path)
+		byPath[path] = append(byPath[path], info)
+	}
+
+	return byPath, nil
+}
+
+func listDownloadInfo(keep func(info *DownloadInfo) bool) ([]*DownloadInfo, error) {
+	entries, err := db.list(trackBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []*DownloadInfo
+	for _, e := range entries {
+		var info DownloadInfo
+		if err := json.Unmarshal(e.Value, &info); err != nil {
+			return nil, err
+		}
+		if keep(&info) {
+			infos = append(infos, &info)
+		}
+	}
+
+	return infos, nil
+}
+
+// migrateDownloads moves the songs downloaded without a profile to profile,
+// as the config always selects a profile once a default one is set, so that
+// they are not downloaded again. Songs profile has downloaded too keep the
+// record of profile.
+func migrateDownloads(profile string) error {
+	if profile == "" {
+		return nil
+	}
+
+	entries, err := db.list(trackBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		var info DownloadInfo
+		if err := json.Unmarshal(e.Value, &info); err != nil {
+			return err
+		}
+		if info.Profile != "" {
+			continue
+		}
+
+		info.Profile = profile
+		value, err := json.Marshal(&info)
+		if err != nil {
+			return err
+		}
+		key := profileKey(profile, info.SongID)
+		ops := []Op{
+			{Bucket: trackBucket, Key: e.Key},
+			{Bucket: trackBucket, Key: key, Value: value},
+		}
+
+		applied, err := db.update([]Cond{{Bucket: trackBucket, Key: key}}, ops)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			if _, err := db.update(nil, ops[:1]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
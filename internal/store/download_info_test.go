@@ -0,0 +1,55 @@
+package store
+
+import (
+	"testing"
+)
+
+func TestMigrateToDefaultProfile(t *testing.T) {
+	dir := t.TempDir()
+	openTestDB(t, dir)
+
+	for _, info := range []*DownloadInfo{
+		{SongID: "1", Path: "/music/1.mp3"},
+		{SongID: "2", Path: "/music/2.mp3"},
+		{SongID: "2", Path: "/home/2.mp3", Profile: "home"},
+	} {
+		if err := info.Save(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := (&WatchedPlaylist{ID: "5"}).Add(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&PlaylistSnapshot{PlaylistID: "5", SongIDs: []string{"1"}}).Save(); err != nil {
+		t.Fatal(err)
+	}
+	Close()
+
+	// A default profile is set in the config
+	openTestDBWithProfile(t, dir, "home")
+
+	for id, want := range map[string]string{
+		"1": "/music/1.mp3",
+		"2": "/home/2.mp3",
+	} {
+		info, err := GetDownloadInfo("home", id)
+		if err != nil {
+			t.Errorf("GetDownloadInfo(home, %s): %v", id, err)
+			continue
+		}
+		if info.Path != want || info.Profile != "home" {
+			t.Errorf("song %s = %+v, want %s downloaded with home", id, info, want)
+		}
+	}
+	if infos, err := ListDownloadInfo(""); err != nil || len(infos) != 0 {
+		t.Errorf("songs without a profile = %d, %v, want none", len(infos), err)
+	}
+
+	if ok, err := IsWatched("home", "5"); err != nil || !ok {
+		t.Errorf("IsWatched(home, 5) = %v, %v, want true", ok, err)
+	}
+	snapshot, err := GetPlaylistSnapshot("home", "5")
+	if err != nil || snapshot == nil || snapshot.Profile != "home" {
+		t.Errorf("snapshot = %+v, %v, want it moved to home", snapshot, err)
+	}
+}
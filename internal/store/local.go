@@ -0,0 +1,161 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+type localDB struct {
+	db      *bolt.DB
+	pidPath string
+}
+
+func openLocal(dbPath, pidPath string, timeout time.Duration) (*localDB, error) {
+	db, err := bolt.Open(dbPath, 0600, &bolt.Options{
+		Timeout:      timeout,
+		FreelistType: bolt.FreelistArrayType,
+	})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, &LockedError{PID: readPID(pidPath)}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())), 0644); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to write pid file: %w", err)
+	}
+
+	return &localDB{db: db, pidPath: pidPath}, nil
+}
+
+func readPID(pidPath string) int {
+	data, err := os.ReadFile(pidPath)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0
+	}
+
+	return pid
+}
+
+func (l *localDB) get(bucket, key []byte) ([]byte, error) {
+	var value []byte
+	err := l.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil
+		}
+		if data := b.Get(key); data != nil {
+			value = append([]byte{}, data...)
+		}
+
+		return nil
+	})
+
+	return value, err
+}
+
+func (l *localDB) put(bucket, key, value []byte) error {
+	return l.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return fmt.Errorf("failed to create bucket: %w", err)
+		}
+
+		return b.Put(key, value)
+	})
+}
+
+func (l *localDB) delete(bucket, key []byte) error {
+	return l.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return fmt.Errorf("bucket not found")
+		}
+
+		return b.Delete(key)
+	})
+}
+
+func (l *localDB) list(bucket []byte) ([]Entry, error) {
+	var entries []Entry
+	err := l.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			entries = append(entries, Entry{
+				Key:   append([]byte{}, k...),
+				Value: append([]byte{}, v...),
+			})
+
+			return nil
+		})
+	})
+
+	return entries, err
+}
+
+func (l *localDB) update(conds []Cond, ops []Op) (bool, error) {
+	applied := false
+	err := l.db.Update(func(tx *bolt.Tx) error {
+		for _, cond := range conds {
+			var value []byte
+			if b := tx.Bucket(cond.Bucket); b != nil {
+				value = b.Get(cond.Key)
+			}
+			if cond.AnyValue && value == nil {
+				return nil
+			}
+			if !cond.AnyValue && ((value == nil) != (cond.Value == nil) || !bytes.Equal(value, cond.Value)) {
+				return nil
+			}
+		}
+
+		for _, op := range ops {
+			if op.Value == nil {
+				if b := tx.Bucket(op.Bucket); b != nil {
+					if err := b.Delete(op.Key); err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			b, err := tx.CreateBucketIfNotExists(op.Bucket)
+			if err != nil {
+				return fmt.Errorf("failed to create bucket: %w", err)
+			}
+			if err := b.Put(op.Key, op.Value); err != nil {
+				return err
+			}
+		}
+		applied = true
+
+		return nil
+	})
+
+	return applied && err == nil, err
+}
+
+func (l *localDB) close() error {
+	if readPID(l.pidPath) == os.Getpid() {
+		os.Remove(l.pidPath)
+	}
+
+	return l.db.Close()
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/rpc"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Request is the argument of every Service method.
+type Request struct {
+	Bucket []byte
+	Key    []byte
+	Value  []byte
+}
+
+// UpdateRequest is the argument of Service.Update.
+type UpdateRequest struct {
+	Conds []Cond
+	Ops   []Op
+}
+
+// Response is the reply of every Service method.
+type Response struct {
+	Value   []byte
+	Entries []Entry
+	// Applied reports whether the conditions of an update held
+	Applied bool
+}
+
+// Service exposes the local database over net/rpc so that a single process
+// can own tracks.db while other invocations read and write through it.
+type Service struct {
+	db *localDB
+}
+
+func (s *Service) Get(req Request, resp *Response) error {
+	value, err := s.db.get(req.Bucket, req.Key)
+	resp.Value = value
+
+	return err
+}
+
+func (s *Service) Put(req Request, resp *Response) error {
+	return s.db.put(req.Bucket, req.Key, req.Value)
+}
+
+func (s *Service) Delete(req Request, resp *Response) error {
+	return s.db.delete(req.Bucket, req.Key)
+}
+
+func (s *Service) List(req Request, resp *Response) error {
+	entries, err := s.db.list(req.Bucket)
+	resp.Entries = entries
+
+	return err
+}
+
+// Update applies a conditional update in a single transaction, so that
+// clients can change a value without losing concurrent changes.
+func (s *Service) Update(req UpdateRequest, resp *Response) error {
+	applied, err := s.db.update(req.Conds, req.Ops)
+	resp.Applied = applied
+
+	return err
+}
+
+// Serve starts serving the database on the Unix socket in the config
+// directory until ctx is done. The current process must own the database.
+func Serve(ctx context.Context) error {
+	local, ok := db.(*localDB)
+	if !ok {
+		return fmt.Errorf("database is already served by another process")
+	}
+
+	socketPath := SocketPath(dbDir)
+	// Anyone who can connect can write to the database. The socket is
+	// created in a directory only the owner can enter, so that nobody else
+	// can connect before the mode of the socket itself is restricted
+	if err := privateDir(filepath.Dir(socketPath)); err != nil {
+		return err
+	}
+	// Owning the database lock means any existing socket is stale
+	os.Remove(socketPath)
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	if err := os.Chmod(socketPath, 0600); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to restrict access to %s: %w", socketPath, err)
+	}
+
+	server := rpc.NewServer()
+	if err := server.RegisterName("Store", &Service{db: local}); err != nil {
+		listener.Close()
+		return err
+	}
+
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.ServeConn(conn)
+		}
+	}()
+
+	return nil
+}
+
+// privateDir creates dir if needed and restricts it to its owner.
+func privateDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	if err := os.Chmod(dir, 0700); err != nil {
+		return fmt.Errorf("failed to restrict access to %s: %w", dir, err)
+	}
+
+	return nil
+}
+
+type remoteDB struct {
+	client *rpc.Client
+}
+
+func dial(socketPath string) (*remoteDB, error) {
+	conn, err := net.DialTimeout("unix", socketPath, time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return &remoteDB{client: rpc.NewClient(conn)}, nil
+}
+
+func (r *remoteDB) get(bucket, key []byte) ([]byte, error) {
+	var resp Response
+	err := r.client.Call("Store.Get", Request{Bucket: bucket, Key: key}, &resp)
+	if len(resp.Value) == 0 {
+		return nil, err
+	}
+
+	return resp.Value, err
+}
+
+func (r *remoteDB) put(bucket, key, value []byte) error {
+	return r.client.Call("Store.Put", Request{Bucket: bucket, Key: key, Value: value}, &Response{})
+}
+
+func (r *remoteDB) delete(bucket, key []byte) error {
+	return r.client.Call("Store.Delete", Request{Bucket: bucket, Key: key}, &Response{})
+}
+
+func (r *remoteDB) list(bucket []byte) ([]Entry, error) {
+	var resp Response
+	err := r.client.Call("Store.List", Request{Bucket: bucket}, &resp)
+
+	return resp.Entries, err
+}
+
+func (r *remoteDB) update(conds []Cond, ops []Op) (bool, error) {
+	var resp Response
+	err := r.client.Call("Store.Update", UpdateRequest{Conds: conds, Ops: ops}, &resp)
+
+	return resp.Applied, err
+}
+
+func (r *remoteDB) close() error {
+	return r.client.Close()
+}
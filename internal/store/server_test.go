@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestServePermissions(t *testing.T) {
+	dir := t.TempDir()
+	openTestDB(t, dir)
+	// A directory left with a wider mode is restricted again
+	if err := os.MkdirAll(filepath.Join(dir, "run"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := Serve(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	socketPath := SocketPath(dir)
+	for path, want := range map[string]os.FileMode{
+		filepath.Dir(socketPath): 0700,
+		socketPath:               0600,
+	} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != want {
+			t.Errorf("mode of %s = %o, want %o", path, mode, want)
+		}
+	}
+
+	remote, err := dial(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.close()
+	if err := remote.put(watchedBucket, []byte("1"), []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	if value, err := db.get(watchedBucket, []byte("1")); err != nil || string(value) != "{}" {
+		t.Errorf("value written through the socket = %q, %v", value, err)
+	}
+}
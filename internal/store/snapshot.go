@@ -0,0 +1,65 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// PlaylistSnapshot is the state of a watched playlist after its last sync.
+// SongIDs only holds songs that have been downloaded, so that songs which
+// failed are picked up again as additions on the next sync. Orphaned holds
+// removed songs whose files have not been handled by the mirror policy yet.
+type PlaylistSnapshot struct {
+	PlaylistID string `json:"playlist_id"`
+	// Profile is the profile watching the playlist
+	Profile  string    `json:"profile,omitempty"`
+	SongIDs  []string  `json:"song_ids"`
+	Checksum string    `json:"checksum"`
+	Added    int       `json:"added"`
+	Removed  []string  `json:"removed"`
+	Orphaned []string  `json:"orphaned"`
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+var snapshotBucket = []byte("snapshots")
+
+// GetPlaylistSnapshot returns the snapshot of a playlist watched by a
+// profile, or nil if the playlist has never been synced.
+func GetPlaylistSnapshot(profile, playlistID string) (*PlaylistSnapshot, error) {
+	data, err := db.get(snapshotBucket, profileKey(profile, playlistID))
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var snapshot PlaylistSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+// ErrNotWatched is returned when saving the snapshot of a playlist that is
+// no longer watched, such as one removed during its sync.
+var ErrNotWatched = errors.New("playlist is no longer watched")
+
+// Save saves the snapshot if the playlist is still watched by its profile,
+// and returns ErrNotWatched otherwise.
+func (s *PlaylistSnapshot) Save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	key := profileKey(s.Profile, s.PlaylistID)
+	ok, err := db.update(
+		[]Cond{{Bucket: watchedBucket, Key: key, AnyValue: true}},
+		[]Op{{Bucket: snapshotBucket, Key: key, Value: data}},
+	)
+	if err == nil && !ok {
+		return ErrNotWatched
+	}
+
+	return err
+}
@@ -3,20 +3,114 @@ package store
 import (
 	"fmt"
 	"path"
+	"time"
+)
+
+// openTimeout is how long a process waits for the database lock before
+// giving up when no watcher is serving the database.
+const openTimeout = 3 * time.Second
+
+// backend is the storage behind the package level helpers. It is either the
+// bbolt database itself, when this process owns tracks.db, or a client of
+// the process that does.
+type backend interface {
+	get(bucket, key []byte) ([]byte, error)
+	put(bucket, key, value []byte) error
+	delete(bucket, key []byte) error
+	list(bucket []byte) ([]Entry, error)
+	// update applies ops in a single transaction if all conds hold, and
+	// reports whether they did
+	update(conds []Cond, ops []Op) (bool, error)
+	close() error
+}
+
+// Entry is a key/value pair stored in a bucket.
+type Entry struct {
+	Key   []byte
+	Value []byte
+}
+
+// Cond is a condition on a key checked by update: the key must hold Value,
+// nil meaning that it is not set, or any value when AnyValue is set.
+type Cond struct {
+	Bucket   []byte
+	Key      []byte
+	Value    []byte
+	AnyValue bool
+}
+
+// Op is a change applied by update: Value is stored under Key, or Key is
+// deleted when Value is nil.
+type Op struct {
+	Bucket []byte
+	Key    []byte
+	Value  []byte
+}
+
+// swap replaces the value of a key with value, nil deleting it, if it
+// still holds old. It reports whether the value was replaced, so that
+// callers can read the key again and retry.
+func swap(bucket, key, old, value []byte) (bool, error) {
+	return db.update([]Cond{{Bucket: bucket, Key: key, Value: old}}, []Op{{Bucket: bucket, Key: key, Value: value}})
+}
+
+// LockedError is returned by OpenDB when another process holds tracks.db
+// and does not serve it over the socket.
+type LockedError struct {
+	PID int
+}
 
-	bolt "go.etcd.io/bbolt"
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return "database locked by another process"
+	}
+
+	return fmt.Sprintf("database locked by PID %d", e.PID)
+}
+
+var (
+	db    backend
+	dbDir string
 )
 
-var db *bolt.DB
+// OpenDB connects to the watcher serving the database if there is one, and
+// otherwise opens tracks.db directly. The records made without a profile
+// are then moved to defaultProfile, the default profile of the config, if
+// there is one.
+func OpenDB(cfgDir, defaultProfile string) error {
+	dbDir = cfgDir
 
-func OpenDB(cfgDir string) error {
-	var err error
+	if remote, err := dial(SocketPath(cfgDir)); err == nil {
+		db = remote
+		return nil
+	}
 
-	dbPath := path.Join(cfgDir, "tracks.db")
-	db, err = bolt.Open(dbPath, 0600, nil)
+	local, err := openLocal(path.Join(cfgDir, "tracks.db"), path.Join(cfgDir, "tracks.pid"), openTimeout)
 	if err != nil {
-		return fmt.Errorf("failed to open database: %w", err)
+		return err
+	}
+	db = local
+
+	if err := migrateWatchedPlaylists(defaultProfile); err != nil {
+		return fmt.Errorf("failed to migrate watched playlists: %w", err)
+	}
+	if err := migrateDownloads(defaultProfile); err != nil {
+		return fmt.Errorf("failed to migrate downloaded songs: %w", err)
 	}
 
 	return nil
 }
+
+func Close() error {
+	if db == nil {
+		return nil
+	}
+
+	return db.close()
+}
+
+// SocketPath returns the path of the socket the watcher serves the database
+// on, in a directory of its own restricted to the owner.
+func SocketPath(cfgDir string) string {
+	return path.Join(cfgDir, "run", "godeez.sock")
+}
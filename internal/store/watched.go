@@ -4,81 +4,198 @@ import (
 	"encoding/json"
 	"fmt"
 	"time"
-
-	bolt "go.etcd.io/bbolt"
 )
 
 type WatchedPlaylist struct {
-	ID      string        `json:"id"`
-	Quality string        `json:"quality"`
-	BPM     bool          `json:"bpm"`
-	Timeout time.Duration `json:"timeout"`
+	ID         string        `json:"id"`
+	Quality    string        `json:"quality"`
+	BPM        bool          `json:"bpm"`
+	Genre      bool          `json:"genre"`
+	Strict     bool          `json:"strict"`
+	Lyrics     bool          `json:"lyrics"`
+	ReplayGain bool          `json:"replaygain"`
+	Timeout    time.Duration `json:"timeout"`
+	OutputDir  string        `json:"output_dir"`
+	Template   string        `json:"template"`
+	Mirror     string        `json:"mirror"`
+	// Profile is the config profile the playlist is downloaded with
+	Profile string `json:"profile,omitempty"`
 }
 
+// Mirror policies applied to the files of songs removed from a watched
+// playlist.
+const (
+	MirrorKeep    = "keep"
+	MirrorArchive = "archive"
+	MirrorDelete  = "delete"
+)
+
 var watchedBucket = []byte("watched")
 
+func GetWatchedPlaylist(profile, playlistID string) (*WatchedPlaylist, error) {
+	data, err := db.get(watchedBucket, profileKey(profile, playlistID))
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		return nil, fmt.Errorf("playlist %s is not being watched", playlistID)
+	}
+
+	var p WatchedPlaylist
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+// MirrorPolicy returns the mirror policy of the playlist, defaulting to
+// MirrorKeep.
+func (p *WatchedPlaylist) MirrorPolicy() string {
+	if p.Mirror == "" {
+		return MirrorKeep
+	}
+
+	return p.Mirror
+}
+
 func ListWatchedPlaylists() ([]*WatchedPlaylist, error) {
+	entries, err := db.list(watchedBucket)
+	if err != nil {
+		return nil, err
+	}
+
 	var playlists []*WatchedPlaylist
-	if err := db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket(watchedBucket)
-		if b == nil {
-			return nil
+	for _, e := range entries {
+		var p WatchedPlaylist
+		if err := json.Unmarshal(e.Value, &p); err != nil {
+			return nil, err
 		}
+		playlists = append(playlists, &p)
+	}
 
-		return b.ForEach(func(k, v []byte) error {
-			var p WatchedPlaylist
-			if err := json.Unmarshal(v, &p); err != nil {
-				return err
-			}
-			playlists = append(playlists, &p)
+	return playlists, nil
+}
 
-			return nil
-		})
-	}); err != nil {
-		return nil, err
+// Add adds the playlist to the watch list of its profile. It returns false
+// when the profile already watches the playlist, which is left unchanged.
+func (p *WatchedPlaylist) Add() (bool, error) {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return false, err
 	}
 
-	return playlists, nil
+	return swap(watchedBucket, profileKey(p.Profile, p.ID), nil, data)
 }
 
-func (p *WatchedPlaylist) Save() error {
-	return db.Update(func(tx *bolt.Tx) error {
-		b, err := tx.CreateBucketIfNotExists(watchedBucket)
+// UpdateWatchedPlaylist applies update to a watched playlist and saves it.
+// update is called again with the new value when another process changed
+// the playlist meanwhile.
+func UpdateWatchedPlaylist(profile, playlistID string, update func(p *WatchedPlaylist)) error {
+	key := profileKey(profile, playlistID)
+	for {
+		old, err := db.get(watchedBucket, key)
 		if err != nil {
 			return err
 		}
+		if old == nil {
+			return fmt.Errorf("playlist %s is not being watched", playlistID)
+		}
 
-		data, err := json.Marshal(p)
+		var p WatchedPlaylist
+		if err := json.Unmarshal(old, &p); err != nil {
+			return err
+		}
+		update(&p)
+		data, err := json.Marshal(&p)
 		if err != nil {
 			return err
 		}
 
-		return b.Put([]byte(p.ID), data)
+		if ok, err := swap(watchedBucket, key, old, data); ok || err != nil {
+			return err
+		}
+	}
+}
+
+// RemoveWatchedPlaylist removes a playlist from the watch list of a profile
+// along with its snapshot, at once so that a sync in progress cannot save
+// the snapshot again.
+func RemoveWatchedPlaylist(profile, playlistID string) error {
+	key := profileKey(profile, playlistID)
+	_, err := db.update(nil, []Op{
+		{Bucket: watchedBucket, Key: key},
+		{Bucket: snapshotBucket, Key: key},
 	})
+
+	return err
 }
 
-func RemoveWatchedPlaylist(playlistID string) error {
-	return db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket(watchedBucket)
-		if b == nil {
-			return fmt.Errorf("bucket not found")
-		}
+func IsWatched(profile, playlistID string) (bool, error) {
+	data, err := db.get(watchedBucket, profileKey(profile, playlistID))
 
-		return b.Delete([]byte(playlistID))
-	})
+	return data != nil, err
 }
 
-func IsWatched(playlistID string) (bool, error) {
-	var found bool
-	err := db.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket(watchedBucket)
-		if b == nil {
-			return nil
+// migrateWatchedPlaylists moves the watched playlists and their snapshots,
+// which used to be keyed by playlist ID alone, to keys including their
+// profile. Playlists watched without a profile are moved to defaultProfile
+// when one is set, as the config then always selects a profile. Entries
+// whose new key is already used are dropped.
+func migrateWatchedPlaylists(defaultProfile string) error {
+	entries, err := db.list(watchedBucket)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		var p WatchedPlaylist
+		if err := json.Unmarshal(e.Value, &p); err != nil {
+			return err
+		}
+		if p.Profile == "" {
+			p.Profile = defaultProfile
+		}
+		key := profileKey(p.Profile, p.ID)
+		if string(e.Key) == string(key) {
+			continue
 		}
-		found = b.Get([]byte(playlistID)) != nil
 
-		return nil
-	})
+		value, err := json.Marshal(&p)
+		if err != nil {
+			return err
+		}
+		ops := []Op{
+			{Bucket: watchedBucket, Key: e.Key},
+			{Bucket: snapshotBucket, Key: e.Key},
+			{Bucket: watchedBucket, Key: key, Value: value},
+		}
+		snapshot, err := db.get(snapshotBucket, e.Key)
+		if err != nil {
+			return err
+		}
+		if snapshot != nil {
+			var s PlaylistSnapshot
+			if err := json.Unmarshal(snapshot, &s); err != nil {
+				return err
+			}
+			s.Profile = p.Profile
+			if snapshot, err = json.Marshal(&s); err != nil {
+				return err
+			}
+			ops = append(ops, Op{Bucket: snapshotBucket, Key: key, Value: snapshot})
+		}
+
+		applied, err := db.update([]Cond{{Bucket: watchedBucket, Key: key}}, ops)
+		if err != nil {
+			return err
+		}
+		if !applied {
+			if _, err := db.update(nil, ops[:2]); err != nil {
+				return err
+			}
+		}
+	}
 
-	return found, err
+	return nil
 }
@@ -0,0 +1,200 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func openTestDB(t *testing.T, dir string) {
+	t.Helper()
+
+	openTestDBWithProfile(t, dir, "")
+}
+
+func openTestDBWithProfile(t *testing.T, dir, defaultProfile string) {
+	t.Helper()
+
+	if err := OpenDB(dir, defaultProfile); err != nil {
+		t.Fatalf("OpenDB: %v", err)
+	}
+	t.Cleanup(func() { Close() })
+}
+
+func TestWatchedPlaylistsByProfile(t *testing.T) {
+	openTestDB(t, t.TempDir())
+
+	for _, profile := range []string{"", "work", "home"} {
+		p := &WatchedPlaylist{ID: "123", Profile: profile, Quality: "flac"}
+		if added, err := p.Add(); err != nil || !added {
+			t.Fatalf("Add(%q) = %v, %v", profile, added, err)
+		}
+		s := &PlaylistSnapshot{PlaylistID: "123", Profile: profile, SongIDs: []string{profile}}
+		if err := s.Save(); err != nil {
+			t.Fatalf("Save snapshot(%q): %v", profile, err)
+		}
+	}
+
+	playlists, err := ListWatchedPlaylists()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(playlists) != 3 {
+		t.Fatalf("watched playlists = %d, want 3", len(playlists))
+	}
+
+	if err := RemoveWatchedPlaylist("work", "123"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		profile string
+		watched bool
+	}{
+		{"", true},
+		{"work", false},
+		{"home", true},
+	} {
+		ok, err := IsWatched(tt.profile, "123")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.watched {
+			t.Errorf("IsWatched(%q) = %v, want %v", tt.profile, ok, tt.watched)
+		}
+
+		snapshot, err := GetPlaylistSnapshot(tt.profile, "123")
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case !tt.watched && snapshot != nil:
+			t.Errorf("snapshot of %q not removed", tt.profile)
+		case tt.watched && (snapshot == nil || snapshot.SongIDs[0] != tt.profile):
+			t.Errorf("snapshot of %q = %+v, want its own", tt.profile, snapshot)
+		}
+	}
+}
+
+func TestMigrateWatchedPlaylists(t *testing.T) {
+	dir := t.TempDir()
+	openTestDB(t, dir)
+
+	// Entries saved before watched playlists were keyed by profile
+	legacy := []struct {
+		key      string
+		playlist WatchedPlaylist
+	}{
+		{"1", WatchedPlaylist{ID: "1", Profile: "work"}},
+		{"2", WatchedPlaylist{ID: "2"}},
+	}
+	for _, l := range legacy {
+		data, _ := json.Marshal(l.playlist)
+		if err := db.put(watchedBucket, []byte(l.key), data); err != nil {
+			t.Fatal(err)
+		}
+		data, _ = json.Marshal(PlaylistSnapshot{PlaylistID: l.playlist.ID, SongIDs: []string{"10"}})
+		if err := db.put(snapshotBucket, []byte(l.key), data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	Close()
+	openTestDB(t, dir)
+
+	for _, tt := range []struct {
+		profile, id string
+	}{
+		{"work", "1"},
+		{"", "2"},
+	} {
+		p, err := GetWatchedPlaylist(tt.profile, tt.id)
+		if err != nil {
+			t.Errorf("GetWatchedPlaylist(%q, %q): %v", tt.profile, tt.id, err)
+			continue
+		}
+		if p.Profile != tt.profile {
+			t.Errorf("playlist %s profile = %q, want %q", tt.id, p.Profile, tt.profile)
+		}
+
+		snapshot, err := GetPlaylistSnapshot(tt.profile, tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snapshot == nil || snapshot.Profile != tt.profile || len(snapshot.SongIDs) != 1 {
+			t.Errorf("snapshot of playlist %s = %+v, want it migrated", tt.id, snapshot)
+		}
+	}
+
+	if ok, _ := IsWatched("", "1"); ok {
+		t.Error("playlist 1 still watched without a profile")
+	}
+	if snapshot, _ := GetPlaylistSnapshot("", "1"); snapshot != nil {
+		t.Error("snapshot of playlist 1 still stored without a profile")
+	}
+}
+
+func TestWatchedPlaylistUpdates(t *testing.T) {
+	dir := t.TempDir()
+	openTestDB(t, dir)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := Serve(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The watcher owns the database while a command goes through the socket
+	local := db
+	remote, err := dial(SocketPath(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer remote.close()
+
+	p := &WatchedPlaylist{ID: "1", Quality: "flac"}
+	db = remote
+	if added, err := p.Add(); err != nil || !added {
+		t.Fatalf("Add = %v, %v", added, err)
+	}
+	if added, err := (&WatchedPlaylist{ID: "1", Quality: "mp3_128"}).Add(); err != nil || added {
+		t.Errorf("second Add = %v, %v, want false", added, err)
+	}
+
+	// A change made by the watcher while the command updates the playlist
+	// is kept
+	calls := 0
+	err = UpdateWatchedPlaylist("", "1", func(p *WatchedPlaylist) {
+		calls++
+		if calls == 1 {
+			db = local
+			if err := UpdateWatchedPlaylist("", "1", func(p *WatchedPlaylist) { p.Lyrics = true }); err != nil {
+				t.Fatal(err)
+			}
+			db = remote
+		}
+		p.Mirror = MirrorDelete
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := GetWatchedPlaylist("", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || !got.Lyrics || got.Mirror != MirrorDelete || got.Quality != "flac" {
+		t.Errorf("playlist = %+v after %d calls, want both changes", got, calls)
+	}
+
+	// The snapshot of a sync finishing after the playlist was removed is
+	// not saved
+	if err := RemoveWatchedPlaylist("", "1"); err != nil {
+		t.Fatal(err)
+	}
+	db = local
+	if err := (&PlaylistSnapshot{PlaylistID: "1"}).Save(); !errors.Is(err, ErrNotWatched) {
+		t.Errorf("Save error = %v, want ErrNotWatched", err)
+	}
+	if snapshot, err := GetPlaylistSnapshot("", "1"); err != nil || snapshot != nil {
+		t.Errorf("snapshot = %+v, %v, want none", snapshot, err)
+	}
+}
@@ -2,6 +2,7 @@ package tags
 
 import (
 	"os"
+	"slices"
 	"strings"
 
 	"github.com/go-flac/flacpicture/v2"
@@ -11,83 +12,168 @@ import (
 )
 
 type flacTagger struct {
-	file  *flac.File
-	cmts  *flacvorbis.MetaDataBlockVorbisComment
-	index int
+	file *flac.File
+	cmts *flacvorbis.MetaDataBlockVorbisComment
 }
 
-func (t *flacTagger) addTags(resource deezer.Resource, song *deezer.Song, cover []byte, path, tempo, key, genre string) error {
-	if album, ok := resource.(*deezer.Album); ok {
-		dateParts := strings.Split(album.Results.Data.PhysicalReleaseDate, "-")
-		if len(dateParts) == 3 {
-			album.Results.Data.PhysicalReleaseDate = dateParts[0]
+func openFLAC(filePath string) (*flacTagger, error) {
+	file, err := flac.ParseFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	cmts, err := extractFLACComment(file)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if cmts == nil {
+		cmts = flacvorbis.New()
+	}
+
+	return &flacTagger{file: file, cmts: cmts}, nil
+}
+
+func (t *flacTagger) addTags(album *deezer.Album, song *deezer.Song, cover []byte, tempo, key, genre string, lyrics *deezer.Lyrics) error {
+	if album != nil && song != nil {
+		// The album is shared by concurrent downloads, so it must not be modified
+		date := album.Results.Data.PhysicalReleaseDate
+		if dateParts := strings.Split(date, "-"); len(dateParts) == 3 {
+			date = dateParts[0]
 		}
 
 		t.addTag("TRACKNUMBER", song.TrackNumber)
+		t.addTag("TOTALTRACKS", album.TotalTracks())
+		t.addTag("DISCNUMBER", song.DiskNumber)
+		t.addTag("TOTALDISCS", album.TotalDiscs())
 		t.addTag("ALBUMARTIST", album.Results.Data.Artist)
 		t.addTag("ALBUM", album.Results.Data.Title)
 		t.addTag("PUBLISHER", album.Results.Data.Label)
 		t.addTag("ORIGINALDATE", album.Results.Data.OriginalReleaseDate)
-		t.addTag("DATE", album.Results.Data.PhysicalReleaseDate)
+		t.addTag("DATE", date)
 		t.addTag("COMMENT", album.Results.Data.ProducerLine)
 		t.addTag("COPYRIGHT", album.Results.Data.Copyright)
 	}
 
-	t.addTag("ARTIST", strings.Join(song.Contributors.MainArtists, ", "))
-	t.addTag("TITLE", song.GetTitle())
-	t.addTag("COMPOSER", strings.Join(song.Contributors.Composers, ", "))
-	t.addTag("LYRICIST", strings.Join(song.Contributors.Authors, ", "))
-	t.addTag("GENRE", genre)
-	t.addTag("REPLAYGAIN_TRACK_GAIN", song.Gain)
-	t.addTag("ISRC", song.ISRC)
+	if song != nil {
+		t.addTag("ARTIST", strings.Join(song.Contributors.MainArtists, ", "))
+		t.addTag("TITLE", song.GetTitle())
+		t.addTag("COMPOSER", strings.Join(song.Contributors.Composers, ", "))
+		t.addTag("LYRICIST", strings.Join(song.Contributors.Authors, ", "))
+		t.addTag("ISRC", song.ISRC)
+	}
 
+	t.addTag("GENRE", genre)
 	t.addTag("BPM", tempo)
 	t.addTag("KEY", key)
 	t.addTag("INITIALKEY", key)
-
-	cmtsmeta := t.cmts.Marshal()
-	if t.index > 0 {
-		t.file.Meta[t.index] = &cmtsmeta
-	} else {
-		t.file.Meta = append(t.file.Meta, &cmtsmeta)
+	if lyrics != nil {
+		t.addTag("LYRICS", lyrics.Text)
 	}
 
-	picture, err := flacpicture.NewFromImageData(flacpicture.PictureTypeFrontCover, "Front cover", cover, "image/jpeg")
-	if err != nil {
-		return err
+	if cover != nil {
+		picture, err := flacpicture.NewFromImageData(flacpicture.PictureTypeFrontCover, "Front cover", cover, "image/jpeg")
+		if err != nil {
+			return err
+		}
+		t.file.Meta = slices.DeleteFunc(t.file.Meta, isFrontCover)
+		picturemeta := picture.Marshal()
+		t.file.Meta = append(t.file.Meta, &picturemeta)
 	}
-	picturemeta := picture.Marshal()
-	t.file.Meta = append(t.file.Meta, &picturemeta)
 
-	return t.saveTags(path)
+	return nil
 }
 
+// addTag replaces the comments named name with value.
 func (t *flacTagger) addTag(name, value string) {
 	if value != "" {
+		t.removeTags(name)
 		t.cmts.Add(name, value)
 	}
 }
 
-func (t *flacTagger) saveTags(path string) error {
+// removeTags removes the comments with one of names, compared without case
+// as vorbis comment names are.
+func (t *flacTagger) removeTags(names ...string) {
+	t.cmts.Comments = slices.DeleteFunc(t.cmts.Comments, func(cmt string) bool {
+		name, _, _ := strings.Cut(cmt, "=")
+		return slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, name) })
+	})
+}
+
+func (t *flacTagger) fields() map[string]string {
+	values := make(map[string][]string)
+	for _, cmt := range t.cmts.Comments {
+		name, value, ok := strings.Cut(cmt, "=")
+		if ok {
+			name = strings.ToUpper(name)
+			values[name] = append(values[name], value)
+		}
+	}
+
+	for _, meta := range t.file.Meta {
+		if meta.Type != flac.Picture {
+			continue
+		}
+		picture, err := flacpicture.ParseFromMetaDataBlock(*meta)
+		if err == nil && picture.PictureType == flacpicture.PictureTypeFrontCover {
+			values[coverField] = append(values[coverField], blobSummary(picture.ImageData))
+		}
+	}
+
+	return joinFields(values)
+}
+
+// setComments replaces the vorbis comment block of the file with the
+// comments of t, or adds it when the file has none.
+func (t *flacTagger) setComments() {
+	cmtsmeta := t.cmts.Marshal()
+	for i, meta := range t.file.Meta {
+		if meta.Type == flac.VorbisComment {
+			t.file.Meta[i] = &cmtsmeta
+			return
+		}
+	}
+	t.file.Meta = append(t.file.Meta, &cmtsmeta)
+}
+
+func (t *flacTagger) save(path string) error {
+	t.setComments()
+
 	tempPath := path + ".tmp"
-	t.file.Save(tempPath)
+	if err := t.file.Save(tempPath); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	// The audio frames are read from the original file until it is saved,
+	// and it cannot be replaced while open on Windows
+	t.close()
 
 	return os.Rename(tempPath, path)
 }
 
-func extractFLACComment(file *flac.File) (*flacvorbis.MetaDataBlockVorbisComment, int, error) {
-	var cmt *flacvorbis.MetaDataBlockVorbisComment
-	var cmtIdx int
-	var err error
-	for idx, meta := range file.Meta {
+func (t *flacTagger) close() {
+	t.file.Close()
+}
+
+// isFrontCover reports whether a metadata block is a front cover picture.
+func isFrontCover(meta *flac.MetaDataBlock) bool {
+	if meta.Type != flac.Picture {
+		return false
+	}
+	picture, err := flacpicture.ParseFromMetaDataBlock(*meta)
+
+	return err == nil && picture.PictureType == flacpicture.PictureTypeFrontCover
+}
+
+// extractFLACComment returns the vorbis comments of a file, nil when it has
+// none. Only the first vorbis comment block is used, as setComments only
+// replaces that one.
+func extractFLACComment(file *flac.File) (*flacvorbis.MetaDataBlockVorbisComment, error) {
+	for _, meta := range file.Meta {
 		if meta.Type == flac.VorbisComment {
-			cmt, err = flacvorbis.ParseFromMetaDataBlock(*meta)
-			cmtIdx = idx
-			if err != nil {
-				return nil, 0, err
-			}
+			return flacvorbis.ParseFromMetaDataBlock(*meta)
 		}
 	}
 
-	return cmt, cmtIdx, nil
+	return nil, nil
 }
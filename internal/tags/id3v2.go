@@ -9,52 +9,89 @@ import (
 	"github.com/mathismqn/godeez/internal/deezer"
 )
 
+// id3Names maps the ID3 text frames written by the tagger to the vorbis
+// comment names of the same tags.
+var id3Names = map[string]string{
+	"TIT2": "TITLE",
+	"TPE1": "ARTIST",
+	"TPE2": "ALBUMARTIST",
+	"TALB": "ALBUM",
+	"TRCK": "TRACKNUMBER",
+	"TPOS": "DISCNUMBER",
+	"TCON": "GENRE",
+	"TCOM": "COMPOSER",
+	"TEXT": "LYRICIST",
+	"TPUB": "PUBLISHER",
+	"TDOR": "ORIGINALDATE",
+	"TYER": "DATE",
+	"TCOP": "COPYRIGHT",
+	"TLEN": "LENGTH",
+	"TBPM": "BPM",
+	"TKEY": "KEY",
+}
+
 type id3v2Tagger struct {
 	tag *id3v2.Tag
 }
 
-func (t *id3v2Tagger) addTags(resource deezer.Resource, song *deezer.Song, cover []byte, path, tempo, key, genre string) error {
-	defer t.tag.Close()
-
-	duration, err := strconv.Atoi(song.Duration)
-	if err != nil {
-		return err
-	}
-	song.Duration = fmt.Sprintf("%d", duration*1000)
-
-	if album, ok := resource.(*deezer.Album); ok {
-		t.addTag("TRCK", song.TrackNumber)
+func (t *id3v2Tagger) addTags(album *deezer.Album, song *deezer.Song, cover []byte, tempo, key, genre string, lyrics *deezer.Lyrics) error {
+	if album != nil && song != nil {
+		t.addTag("TRCK", numberOf(song.TrackNumber, album.TotalTracks()))
+		t.addTag("TPOS", numberOf(song.DiskNumber, album.TotalDiscs()))
 		t.addTag("TPE2", album.Results.Data.Artist)
 		t.addTag("TALB", album.Results.Data.Title)
 		t.addTag("TPUB", album.Results.Data.Label)
 		t.addTag("TDOR", album.Results.Data.OriginalReleaseDate)
 		t.addTag("TYER", album.Results.Data.PhysicalReleaseDate)
-		t.addTag("COMM", album.Results.Data.ProducerLine)
+		t.addComment(album.Results.Data.ProducerLine)
 		t.addTag("TCOP", album.Results.Data.Copyright)
 	}
 
-	t.addTag("TPE1", strings.Join(song.Contributors.MainArtists, ", "))
-	t.addTag("TIT2", song.GetTitle())
-	t.addTag("TCOM", strings.Join(song.Contributors.Composers, ", "))
-	t.addTag("TEXT", strings.Join(song.Contributors.Authors, ", "))
-	t.addTag("TCON", genre)
-	t.addTag("TLEN", song.Duration)
-	t.addTXXXTag("GAIN", song.Gain)
-	t.addTXXXTag("ISRC", song.ISRC)
+	if song != nil {
+		t.addTag("TPE1", strings.Join(song.Contributors.MainArtists, ", "))
+		t.addTag("TIT2", song.GetTitle())
+		t.addTag("TCOM", strings.Join(song.Contributors.Composers, ", "))
+		t.addTag("TEXT", strings.Join(song.Contributors.Authors, ", "))
+		if duration, err := strconv.Atoi(song.Duration); err == nil {
+			t.addTag("TLEN", fmt.Sprintf("%d", duration*1000))
+		}
+		t.addTXXXTag("ISRC", song.ISRC)
+	}
 
+	t.addTag("TCON", genre)
 	t.addTag("TBPM", tempo)
 	t.addTag("TKEY", key)
 
-	frame := id3v2.PictureFrame{
-		Encoding:    t.tag.DefaultEncoding(),
-		MimeType:    "image/jpeg",
-		PictureType: id3v2.PTFrontCover,
-		Description: "Cover",
-		Picture:     cover,
+	if lyrics != nil {
+		// Parsed SYLT frames are unknown to the id3v2 package and would
+		// not be replaced by the new one
+		t.tag.DeleteFrames("USLT")
+		t.tag.DeleteFrames("SYLT")
+		t.tag.AddUnsynchronisedLyricsFrame(id3v2.UnsynchronisedLyricsFrame{
+			Encoding: t.tag.DefaultEncoding(),
+			Language: unknownLanguage,
+			Lyrics:   lyrics.Text,
+		})
+		if len(lyrics.Synced) > 0 {
+			t.tag.AddFrame("SYLT", newSYLTFrame(t.tag.DefaultEncoding(), lyrics.Synced))
+		}
 	}
-	t.tag.AddAttachedPicture(frame)
 
-	return t.tag.Save()
+	if cover != nil {
+		t.deleteFrames("APIC", func(frame id3v2.Framer) bool {
+			picture, ok := frame.(id3v2.PictureFrame)
+			return ok && picture.PictureType == id3v2.PTFrontCover
+		})
+		t.tag.AddAttachedPicture(id3v2.PictureFrame{
+			Encoding:    t.tag.DefaultEncoding(),
+			MimeType:    "image/jpeg",
+			PictureType: id3v2.PTFrontCover,
+			Description: "Cover",
+			Picture:     cover,
+		})
+	}
+
+	return nil
 }
 
 func (t *id3v2Tagger) addTag(name, value string) {
@@ -73,3 +110,71 @@ func (t *id3v2Tagger) addTXXXTag(description, value string) {
 		t.tag.AddUserDefinedTextFrame(udf)
 	}
 }
+
+// addComment replaces the comments of the tag with value.
+func (t *id3v2Tagger) addComment(value string) {
+	if value == "" {
+		return
+	}
+
+	t.tag.DeleteFrames("COMM")
+	t.tag.AddCommentFrame(id3v2.CommentFrame{
+		Encoding: t.tag.DefaultEncoding(),
+		Language: unknownLanguage,
+		Text:     value,
+	})
+}
+
+// deleteFrames deletes the frames with id matching match. The id3v2
+// package can only delete all the frames of an id, so the other ones are
+// added back.
+func (t *id3v2Tagger) deleteFrames(id string, match func(frame id3v2.Framer) bool) {
+	frames := t.tag.GetFrames(id)
+	t.tag.DeleteFrames(id)
+	for _, frame := range frames {
+		if !match(frame) {
+			t.tag.AddFrame(id, frame)
+		}
+	}
+}
+
+func (t *id3v2Tagger) fields() map[string]string {
+	values := make(map[string][]string)
+	for id, frames := range t.tag.AllFrames() {
+		for _, frame := range frames {
+			switch f := frame.(type) {
+			case id3v2.UserDefinedTextFrame:
+				name := strings.ToUpper(f.Description)
+				values[name] = append(values[name], f.Value)
+			case id3v2.TextFrame:
+				if name, ok := id3Names[id]; ok {
+					values[name] = append(values[name], f.Text)
+				}
+			case id3v2.CommentFrame:
+				values["COMMENT"] = append(values["COMMENT"], f.Text)
+			case id3v2.UnsynchronisedLyricsFrame:
+				values["LYRICS"] = append(values["LYRICS"], f.Lyrics)
+			case id3v2.PictureFrame:
+				if f.PictureType == id3v2.PTFrontCover {
+					values[coverField] = append(values[coverField], blobSummary(f.Picture))
+				}
+			case syltFrame:
+				values[syncedLyricsField] = append(values[syncedLyricsField], blobSummary(f.body()))
+			case id3v2.UnknownFrame:
+				if id == "SYLT" {
+					values[syncedLyricsField] = append(values[syncedLyricsField], blobSummary(f.Body))
+				}
+			}
+		}
+	}
+
+	return joinFields(values)
+}
+
+func (t *id3v2Tagger) save(string) error {
+	return t.tag.Save()
+}
+
+func (t *id3v2Tagger) close() {
+	t.tag.Close()
+}
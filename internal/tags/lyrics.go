@@ -0,0 +1,110 @@
+package tags
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/mathismqn/godeez/internal/deezer"
+)
+
+// syncedLyricsField is the name under which fields reports the SYLT frame,
+// which has no vorbis comment counterpart.
+const syncedLyricsField = "SYNCEDLYRICS"
+
+// syltFrame is a synchronised lyrics frame (SYLT), which the id3v2 package
+// does not provide. Time stamps are in milliseconds.
+type syltFrame struct {
+	encoding id3v2.Encoding
+	lines    []deezer.LyricsLine
+}
+
+func newSYLTFrame(tagEncoding id3v2.Encoding, lines []deezer.LyricsLine) syltFrame {
+	// Unicode text is written as UTF-8 in ID3v2.4 tags and as UTF-16 in
+	// older ones, which do not support UTF-8
+	encoding := id3v2.EncodingUTF16
+	if tagEncoding.Equals(id3v2.EncodingUTF8) {
+		encoding = id3v2.EncodingUTF8
+	}
+
+	return syltFrame{encoding: encoding, lines: lines}
+}
+
+func (f syltFrame) body() []byte {
+	var b bytes.Buffer
+	b.WriteByte(f.encoding.Key)
+	b.WriteString(unknownLanguage)
+	b.WriteByte(2)      // time stamps in milliseconds
+	b.WriteByte(1)      // content type: lyrics
+	f.writeText(&b, "") // content descriptor
+
+	for _, line := range f.lines {
+		f.writeText(&b, line.Text)
+		binary.Write(&b, binary.BigEndian, uint32(line.Time.Milliseconds()))
+	}
+
+	return b.Bytes()
+}
+
+func (f syltFrame) writeText(b *bytes.Buffer, s string) {
+	if f.encoding.Equals(id3v2.EncodingUTF8) {
+		b.WriteString(s)
+	} else {
+		// Little-endian with its byte order mark
+		b.Write([]byte{0xFF, 0xFE})
+		for _, u := range utf16.Encode([]rune(s)) {
+			b.Write([]byte{byte(u), byte(u >> 8)})
+		}
+	}
+	b.Write(f.encoding.TerminationBytes)
+}
+
+func (f syltFrame) Size() int {
+	return len(f.body())
+}
+
+func (f syltFrame) UniqueIdentifier() string {
+	return unknownLanguage
+}
+
+func (f syltFrame) WriteTo(w io.Writer) (int64, error) {
+	n, err := w.Write(f.body())
+
+	return int64(n), err
+}
+
+// LRCPath returns the path of the .lrc file holding the synchronised
+// lyrics of an audio file.
+func LRCPath(audioPath string) string {
+	return strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".lrc"
+}
+
+// WriteLRC writes the synchronised lyrics of a song to a .lrc file next to
+// its audio file, which players pick up by name.
+func WriteLRC(audioPath string, song *deezer.Song, lyrics *deezer.Lyrics) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[ar:%s]\n", song.Artist)
+	fmt.Fprintf(&b, "[ti:%s]\n", song.GetTitle())
+	if song.AlbumTitle != "" {
+		fmt.Fprintf(&b, "[al:%s]\n", song.AlbumTitle)
+	}
+	for _, line := range lyrics.Synced {
+		fmt.Fprintf(&b, "[%s]%s\n", lrcTimestamp(line.Time), line.Text)
+	}
+
+	return os.WriteFile(LRCPath(audioPath), []byte(b.String()), 0644)
+}
+
+// lrcTimestamp formats a time as mm:ss.xx.
+func lrcTimestamp(d time.Duration) string {
+	centiseconds := d.Milliseconds() / 10
+
+	return fmt.Sprintf("%02d:%02d.%02d", centiseconds/6000, centiseconds/100%60, centiseconds%100)
+}
@@ -0,0 +1,97 @@
+package tags
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/mathismqn/godeez/internal/replaygain"
+)
+
+// ReplayGain tag names. ID3 tags store them in TXXX frames with the same
+// description, as foobar2000 and loudgain do.
+const (
+	trackGainTag = "REPLAYGAIN_TRACK_GAIN"
+	trackPeakTag = "REPLAYGAIN_TRACK_PEAK"
+	albumGainTag = "REPLAYGAIN_ALBUM_GAIN"
+	albumPeakTag = "REPLAYGAIN_ALBUM_PEAK"
+	// legacyGainTag is the TXXX frame older versions filled with the raw
+	// gain given by Deezer, replaced by the computed values
+	legacyGainTag = "GAIN"
+)
+
+var replayGainTags = []string{trackGainTag, trackPeakTag, albumGainTag, albumPeakTag}
+
+// WriteReplayGain writes ReplayGain values to the file at filePath,
+// replacing the ones it already has. The album values of the file are
+// removed when values has none, as they would no longer match the track.
+func WriteReplayGain(filePath string, values replaygain.Values) error {
+	_, err := UpdateReplayGain(filePath, values, false)
+
+	return err
+}
+
+// UpdateReplayGain is like WriteReplayGain and returns the tags whose value
+// changed, sorted by name. The file is left unchanged when dryRun is set.
+func UpdateReplayGain(filePath string, values replaygain.Values, dryRun bool) ([]Change, error) {
+	fields := [][2]string{
+		{trackGainTag, formatGain(values.TrackGain)},
+		{trackPeakTag, formatPeak(values.TrackPeak)},
+	}
+	if values.HasAlbum {
+		fields = append(fields,
+			[2]string{albumGainTag, formatGain(values.AlbumGain)},
+			[2]string{albumPeakTag, formatPeak(values.AlbumPeak)},
+		)
+	}
+
+	t, err := newTagger(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer t.close()
+
+	old := t.fields()
+	t.setReplayGain(fields)
+	changes := diff(old, t.fields())
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	return changes, t.save(filePath)
+}
+
+func (t *id3v2Tagger) setReplayGain(fields [][2]string) {
+	t.deleteFrames("TXXX", func(frame id3v2.Framer) bool {
+		udf, ok := frame.(id3v2.UserDefinedTextFrame)
+		return ok && (isReplayGainTag(udf.Description) || strings.EqualFold(udf.Description, legacyGainTag))
+	})
+	for _, field := range fields {
+		t.addTXXXTag(field[0], field[1])
+	}
+}
+
+func (t *flacTagger) setReplayGain(fields [][2]string) {
+	t.removeTags(replayGainTags...)
+	for _, field := range fields {
+		t.addTag(field[0], field[1])
+	}
+}
+
+func isReplayGainTag(name string) bool {
+	for _, tag := range replayGainTags {
+		if strings.EqualFold(name, tag) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func formatGain(gain float64) string {
+	return fmt.Sprintf("%.2f dB", gain)
+}
+
+func formatPeak(peak float64) string {
+	return fmt.Sprintf("%.6f", peak)
+}
@@ -0,0 +1,105 @@
+package tags
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bogem/id3v2/v2"
+	"github.com/mathismqn/godeez/internal/deezertest"
+	"github.com/mathismqn/godeez/internal/replaygain"
+)
+
+func TestUpdateReplayGainRemovesLegacyGain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.mp3")
+	if err := os.WriteFile(path, deezertest.SilentMP3(1), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Older versions wrote the raw gain of Deezer to a GAIN frame
+	tag, err := id3v2.Open(path, id3v2.Options{Parse: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag.AddUserDefinedTextFrame(id3v2.UserDefinedTextFrame{Encoding: id3v2.EncodingUTF8, Description: "GAIN", Value: "-7.5"})
+	if err := tag.Save(); err != nil {
+		t.Fatal(err)
+	}
+	tag.Close()
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := replaygain.Values{TrackGain: -2.1, TrackPeak: 0.5}
+	want := []Change{
+		{Name: "GAIN", Old: "-7.5"},
+		{Name: trackGainTag, New: "-2.10 dB"},
+		{Name: trackPeakTag, New: "0.500000"},
+	}
+
+	changes, err := UpdateReplayGain(path, values, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("dry run changes = %+v, want %+v", changes, want)
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Error("dry run changed the file")
+	}
+
+	changes, err = UpdateReplayGain(path, values, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+	fields, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["GAIN"]; ok {
+		t.Error("GAIN frame not removed")
+	}
+
+	changes, err = UpdateReplayGain(path, values, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) > 0 {
+		t.Errorf("changes after writing the same values = %+v, want none", changes)
+	}
+}
+
+func TestUpdateReplayGainRemovesAlbumValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "song.flac")
+	if err := os.WriteFile(path, deezertest.ToneFLAC(1, 997, 0.5), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	album := replaygain.Values{TrackGain: -2.1, TrackPeak: 0.5, HasAlbum: true, AlbumGain: -3, AlbumPeak: 0.6}
+	if err := WriteReplayGain(path, album); err != nil {
+		t.Fatal(err)
+	}
+
+	// The song is no longer analysed with its album
+	changes, err := UpdateReplayGain(path, replaygain.Values{TrackGain: -2.1, TrackPeak: 0.5}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Name: albumGainTag, Old: "-3.00 dB"},
+		{Name: albumPeakTag, Old: "0.600000"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("changes = %+v, want %+v", changes, want)
+	}
+}
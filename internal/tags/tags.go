@@ -1,16 +1,33 @@
 package tags
 
 import (
+	"fmt"
+	"hash/crc32"
 	"path"
+	"sort"
+	"strings"
 
 	"github.com/bogem/id3v2/v2"
-	"github.com/go-flac/flacvorbis/v2"
-	"github.com/go-flac/go-flac/v2"
 	"github.com/mathismqn/godeez/internal/deezer"
 )
 
+// unknownLanguage is the ISO 639-2 code of the lyrics and comment frames,
+// "XXX" standing for an unknown language as Deezer does not tell it.
+const unknownLanguage = "XXX"
+
+// coverField is the name under which fields reports the size of the front
+// cover, so that a new cover shows in the changes of UpdateTags.
+const coverField = "COVER"
+
+// tagger edits the tags of a file in memory until they are saved.
 type tagger interface {
-	addTags(resource deezer.Resource, song *deezer.Song, cover []byte, path, tempo, key, genre string) error
+	addTags(album *deezer.Album, song *deezer.Song, cover []byte, tempo, key, genre string, lyrics *deezer.Lyrics) error
+	// fields returns the text tags, named as vorbis comments in upper case
+	fields() map[string]string
+	// setReplayGain replaces the ReplayGain tags with fields
+	setReplayGain(fields [][2]string)
+	save(path string) error
+	close()
 }
 
 func newTagger(filePath string) (tagger, error) {
@@ -24,26 +41,106 @@ func newTagger(filePath string) (tagger, error) {
 		return &id3v2Tagger{tag: tag}, nil
 	}
 
-	file, err := flac.ParseFile(filePath)
+	return openFLAC(filePath)
+}
+
+// AddTags writes the tags of a song to the file at filePath, replacing the
+// values the file already has. The album tags are only written when album
+// is not nil, the song tags when song is not nil, the cover when cover is
+// not nil and the lyrics when lyrics is not nil. Empty values are not
+// written and leave the existing ones in place.
+func AddTags(album *deezer.Album, song *deezer.Song, cover []byte, filePath, tempo, key, genre string, lyrics *deezer.Lyrics) error {
+	_, err := UpdateTags(album, song, cover, filePath, tempo, key, genre, lyrics, false)
+
+	return err
+}
+
+// Change is a tag whose value is changed, named as a vorbis comment. Old or
+// New is empty when the tag is added or removed.
+type Change struct {
+	Name string
+	Old  string
+	New  string
+}
+
+// UpdateTags is like AddTags and returns the tags whose value changed,
+// sorted by name. The file is left unchanged when dryRun is set.
+func UpdateTags(album *deezer.Album, song *deezer.Song, cover []byte, filePath, tempo, key, genre string, lyrics *deezer.Lyrics, dryRun bool) ([]Change, error) {
+	t, err := newTagger(filePath)
 	if err != nil {
 		return nil, err
 	}
-	cmts, idx, err := extractFLACComment(file)
-	if err != nil {
+	defer t.close()
+
+	old := t.fields()
+	if err := t.addTags(album, song, cover, tempo, key, genre, lyrics); err != nil {
 		return nil, err
 	}
-	if cmts == nil && idx > 0 {
-		cmts = flacvorbis.New()
+	changes := diff(old, t.fields())
+	if dryRun || len(changes) == 0 {
+		return changes, nil
 	}
 
-	return &flacTagger{file: file, cmts: cmts, index: idx}, nil
+	return changes, t.save(filePath)
 }
 
-func AddTags(resource deezer.Resource, song *deezer.Song, cover []byte, filePath, tempo, key, genre string) error {
-	tagger, err := newTagger(filePath)
+// Read returns the text tags of the file at filePath, named as vorbis
+// comments in upper case whatever the format of the file. User defined ID3
+// frames are named after their description, and tags with several values
+// have them joined with "; ".
+func Read(filePath string) (map[string]string, error) {
+	t, err := newTagger(filePath)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer t.close()
+
+	return t.fields(), nil
+}
+
+// diff returns the changes from old to new, sorted by name. Tags of old
+// missing from new are removed tags, with an empty New.
+func diff(old, new map[string]string) []Change {
+	var changes []Change
+	for name, value := range new {
+		if old[name] != value {
+			changes = append(changes, Change{Name: name, Old: old[name], New: value})
+		}
+	}
+	for name, value := range old {
+		if _, ok := new[name]; !ok {
+			changes = append(changes, Change{Name: name, Old: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes
+}
+
+// joinFields joins the values of each tag with "; ".
+func joinFields(values map[string][]string) map[string]string {
+	fields := make(map[string]string, len(values))
+	for name, v := range values {
+		fields[name] = strings.Join(v, "; ")
+	}
+
+	return fields
+}
+
+// blobSummary describes binary data by its size and checksum, so that it
+// can be compared and shown as a text tag.
+func blobSummary(data []byte) string {
+	return fmt.Sprintf("%d bytes, crc32 %08x", len(data), crc32.ChecksumIEEE(data))
+}
+
+// numberOf returns "n/total" as written in the TRCK and TPOS frames, or n
+// alone when the total is unknown.
+func numberOf(n, total string) string {
+	if n == "" || total == "" {
+		return n
 	}
 
-	return tagger.addTags(resource, song, cover, filePath, tempo, key, genre)
+	return n + "/" + total
 }
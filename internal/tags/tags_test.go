@@ -0,0 +1,56 @@
+package tags
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new map[string]string
+		want     []Change
+	}{
+		{
+			name: "unchanged",
+			old:  map[string]string{"TITLE": "Aerodynamic"},
+			new:  map[string]string{"TITLE": "Aerodynamic"},
+		},
+		{
+			name: "added",
+			old:  map[string]string{},
+			new:  map[string]string{"GENRE": "House"},
+			want: []Change{{Name: "GENRE", New: "House"}},
+		},
+		{
+			name: "changed",
+			old:  map[string]string{"BPM": "120"},
+			new:  map[string]string{"BPM": "123"},
+			want: []Change{{Name: "BPM", Old: "120", New: "123"}},
+		},
+		{
+			name: "removed",
+			old:  map[string]string{"GAIN": "-7.5", "TITLE": "Aerodynamic"},
+			new:  map[string]string{"TITLE": "Aerodynamic"},
+			want: []Change{{Name: "GAIN", Old: "-7.5"}},
+		},
+		{
+			name: "sorted by name",
+			old:  map[string]string{"GAIN": "-7.5", "BPM": "120", "TITLE": "Aerodynamic"},
+			new:  map[string]string{"REPLAYGAIN_TRACK_GAIN": "-2.10 dB", "BPM": "123", "TITLE": "Aerodynamic"},
+			want: []Change{
+				{Name: "BPM", Old: "120", New: "123"},
+				{Name: "GAIN", Old: "-7.5"},
+				{Name: "REPLAYGAIN_TRACK_GAIN", New: "-2.10 dB"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diff(tt.old, tt.new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diff = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package template
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/flytam/filenamify"
+)
+
+// Placeholders lists the fields that can be used in a template, e.g.
+// "{albumartist}/{year} - {album}/{track} {title}".
+var Placeholders = []string{
+	"id",
+	"title",
+	"version",
+	"artist",
+	"artists",
+	"albumartist",
+	"album",
+	"track",
+	"tracknumber",
+	"totaltracks",
+	"disc",
+	"totaldiscs",
+	"year",
+	"date",
+	"label",
+	"isrc",
+	"resource",
+	"type",
+}
+
+var placeholderRegex = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// Template is a path template relative to the output directory. Each "/"
+// separated segment becomes a directory, the last one being the file name
+// without its extension.
+type Template struct {
+	segments []string
+}
+
+func Parse(s string) (*Template, error) {
+	s = strings.TrimSpace(strings.ReplaceAll(s, "\\", "/"))
+	if s == "" {
+		return nil, fmt.Errorf("template is empty")
+	}
+
+	segments := strings.Split(strings.Trim(s, "/"), "/")
+	for _, segment := range segments {
+		if segment == "" || segment == "." || segment == ".." {
+			return nil, fmt.Errorf("invalid template %q: empty or relative path segment", s)
+		}
+
+		for _, match := range placeholderRegex.FindAllStringSubmatch(segment, -1) {
+			if !slices.Contains(Placeholders, match[1]) {
+				return nil, fmt.Errorf("invalid template %q: unknown placeholder {%s}", s, match[1])
+			}
+		}
+	}
+
+	return &Template{segments: segments}, nil
+}
+
+// Uses reports whether the template contains any of the placeholders.
+func (t *Template) Uses(placeholders ...string) bool {
+	for _, segment := range t.segments {
+		for _, match := range placeholderRegex.FindAllStringSubmatch(segment, -1) {
+			if slices.Contains(placeholders, match[1]) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Render replaces the placeholders with the given fields and sanitises each
+// segment separately, so that a "/" in a value never creates a directory.
+func (t *Template) Render(fields map[string]string) string {
+	parts := make([]string, 0, len(t.segments))
+	for _, segment := range t.segments {
+		value := placeholderRegex.ReplaceAllStringFunc(segment, func(m string) string {
+			return fields[m[1:len(m)-1]]
+		})
+
+		// Missing fields leave dangling separators such as " - Album"
+		value = strings.Trim(strings.Join(strings.Fields(value), " "), " -")
+		value, _ = filenamify.Filenamify(value, filenamify.Options{MaxLength: 250})
+		// Reserved characters at the end are dropped, leaving the space
+		// before them, which Windows does not allow at the end of a name
+		value = strings.TrimSpace(value)
+		if value == "" {
+			value = "Unknown"
+		}
+		parts = append(parts, value)
+	}
+
+	return filepath.Join(parts...)
+}
@@ -0,0 +1,116 @@
+package template
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		wantErr  string
+	}{
+		{name: "default album", template: "{albumartist} - {album}/{tracknumber}. {artist} - {title}"},
+		{name: "backslashes", template: `{artist}\{title}`},
+		{name: "leading and trailing slashes", template: "/{artist}/{title}/"},
+		{name: "empty", template: "  ", wantErr: "template is empty"},
+		{name: "unknown placeholder", template: "{artist}/{songtitle}", wantErr: "unknown placeholder {songtitle}"},
+		{name: "empty segment", template: "{artist}//{title}", wantErr: "empty or relative path segment"},
+		{name: "parent directory", template: "../{title}", wantErr: "empty or relative path segment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.template)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Parse(%q) failed: %v", tt.template, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %q", tt.template, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		fields   map[string]string
+		want     string
+	}{
+		{
+			name:     "all fields",
+			template: "{albumartist} - {album}/{disc}-{track}. {artist} - {title}",
+			fields:   map[string]string{"albumartist": "Daft Punk", "album": "Discovery", "disc": "1", "track": "03", "artist": "Daft Punk", "title": "Digital Love"},
+			want:     "Daft Punk - Discovery/1-03. Daft Punk - Digital Love",
+		},
+		{
+			name:     "missing field trims separators",
+			template: "{albumartist} - {album}/{artist} - {title}",
+			fields:   map[string]string{"album": "Discovery", "artist": "Daft Punk", "title": "One More Time"},
+			want:     "Discovery/Daft Punk - One More Time",
+		},
+		{
+			name:     "missing fields collapse spaces",
+			template: "{artist} {version} {title}",
+			fields:   map[string]string{"artist": "Daft Punk", "title": "Aerodynamic"},
+			want:     "Daft Punk Aerodynamic",
+		},
+		{
+			name:     "empty segment",
+			template: "{label}/{year} - {album}/{title}",
+			fields:   map[string]string{"title": "Voyager"},
+			want:     "Unknown/Unknown/Voyager",
+		},
+		{
+			name:     "slash in value",
+			template: "{artist}/{title}",
+			fields:   map[string]string{"artist": "AC/DC", "title": "Back In Black"},
+			want:     "AC!DC/Back In Black",
+		},
+		{
+			name:     "reserved characters",
+			template: "{title}",
+			fields:   map[string]string{"title": `What? <Why>: "Who" *|\`},
+			want:     "What! !Why! !Who!",
+		},
+		{
+			name:     "track and disc as given",
+			template: "{disc}-{track}. {title}",
+			fields:   map[string]string{"disc": "2", "track": "07", "title": "Something About Us"},
+			want:     "2-07. Something About Us",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.template)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, want := tmpl.Render(tt.fields), filepath.FromSlash(tt.want); got != want {
+				t.Errorf("Render() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestUses(t *testing.T) {
+	tmpl, err := Parse("{albumartist}/{track} {title}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !tmpl.Uses("label", "albumartist") {
+		t.Error("Uses(label, albumartist) = false, want true")
+	}
+	if tmpl.Uses("label", "album") {
+		t.Error("Uses(label, album) = true, want false")
+	}
+}
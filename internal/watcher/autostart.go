@@ -12,9 +12,7 @@ func EnsureAutostart(homeDir string) error {
 		return nil
 	}
 
-	// return installAutostart(homeDir)
-
-	return nil
+	return installAutostart(homeDir)
 }
 
 func isAutostartInstalled(homeDir string) bool {
@@ -0,0 +1,7 @@
+//go:build !darwin
+
+package watcher
+
+func installAutostart(homeDir string) error {
+	return nil
+}
@@ -0,0 +1,207 @@
+package watcher
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mathismqn/godeez/internal/fileutil"
+	"github.com/mathismqn/godeez/internal/store"
+	"github.com/mathismqn/godeez/internal/tags"
+)
+
+// MirrorAction describes what the mirror policy of a watched playlist does
+// with the file of a song removed from it.
+type MirrorAction struct {
+	SongID string
+	Path   string
+	// Dest is the archive location, empty when the file is deleted
+	Dest string
+	// Skip explains why the file is kept, empty when the action applies
+	Skip string
+}
+
+func (a MirrorAction) String() string {
+	switch {
+	case a.Skip != "" && a.Path != "":
+		return fmt.Sprintf("keep    %s (%s)", a.Path, a.Skip)
+	case a.Skip != "":
+		return fmt.Sprintf("keep    song %s (%s)", a.SongID, a.Skip)
+	case a.Dest != "":
+		return fmt.Sprintf("archive %s -> %s", a.Path, a.Dest)
+	default:
+		return fmt.Sprintf("delete  %s", a.Path)
+	}
+}
+
+// PlanMirror lists what the mirror policy of a playlist would do with the
+// orphaned songs of its snapshot, without touching any file. A file is only
+// archived or deleted once no other resource owns it, with this profile or
+// another one saving to the same file: a download of another resource, or
+// another watched playlist that still has the song.
+func PlanMirror(playlist *store.WatchedPlaylist, snapshot *store.PlaylistSnapshot, outputDir string) ([]MirrorAction, error) {
+	policy := playlist.MirrorPolicy()
+	if policy == store.MirrorKeep || snapshot == nil || len(snapshot.Orphaned) == 0 {
+		return nil, nil
+	}
+
+	watched, err := songsOfOtherPlaylists(playlist)
+	if err != nil {
+		return nil, err
+	}
+	byPath, err := store.DownloadInfoByPath()
+	if err != nil {
+		return nil, err
+	}
+
+	actions := make([]MirrorAction, 0, len(snapshot.Orphaned))
+	for _, id := range snapshot.Orphaned {
+		action := MirrorAction{SongID: id}
+
+		info, err := store.GetDownloadInfo(playlist.Profile, id)
+		if err != nil {
+			action.Skip = "not downloaded"
+			actions = append(actions, action)
+			continue
+		}
+		action.Path = info.Path
+
+		owner := otherOwner(playlist, byPath[filepath.Clean(info.Path)], watched)
+		switch {
+		case owner != "":
+			action.Skip = owner
+		case !fileutil.FileExists(info.Path):
+			action.Skip = "file not found"
+		case policy == store.MirrorArchive:
+			action.Dest = archivePath(outputDir, info.Path)
+		}
+		actions = append(actions, action)
+	}
+
+	return actions, nil
+}
+
+// otherOwner describes a resource other than playlist that owns the file of
+// the songs in infos, empty when there is none. Playlists only own the songs
+// they have while they are watched.
+func otherOwner(playlist *store.WatchedPlaylist, infos []*store.DownloadInfo, watched map[profileSong]string) string {
+	for _, info := range infos {
+		if id, ok := watched[profileSong{info.Profile, info.SongID}]; ok {
+			return "still in watched playlist " + id + profileSuffix(playlist, info)
+		}
+		for _, owner := range info.Owners() {
+			if strings.HasPrefix(owner, "playlist/") {
+				continue
+			}
+			return "also downloaded for " + owner + profileSuffix(playlist, info)
+		}
+	}
+
+	return ""
+}
+
+// profileSuffix names the profile of info when it is not the one of
+// playlist.
+func profileSuffix(playlist *store.WatchedPlaylist, info *store.DownloadInfo) string {
+	switch {
+	case info.Profile == playlist.Profile:
+		return ""
+	case info.Profile == "":
+		return " without a profile"
+	default:
+		return " of profile " + info.Profile
+	}
+}
+
+// applyMirror runs the actions of a mirror plan and returns the IDs of the
+// songs that still need to be handled because their action failed.
+func (w *Watcher) applyMirror(playlist *store.WatchedPlaylist, actions []MirrorAction) []string {
+	var pending []string
+	for _, action := range actions {
+		if action.Skip != "" {
+			continue
+		}
+
+		if err := runMirrorAction(action); err != nil {
+			w.logger.Errorf("Playlist %s: failed to %s: %v\n", playlist.ID, action, err)
+			pending = append(pending, action.SongID)
+			continue
+		}
+
+		if err := store.DeleteDownloadInfo(playlist.Profile, action.SongID); err != nil {
+			w.logger.Warnf("Playlist %s: failed to delete download info of song %s: %v\n", playlist.ID, action.SongID, err)
+		}
+	}
+
+	return pending
+}
+
+func runMirrorAction(action MirrorAction) error {
+	lrcPath := tags.LRCPath(action.Path)
+	if action.Dest == "" {
+		if err := os.Remove(action.Path); err != nil {
+			return err
+		}
+		// The lyrics file goes with the song, if there is one
+		fileutil.DeleteFile(lrcPath)
+		return nil
+	}
+
+	if err := fileutil.EnsureDir(filepath.Dir(action.Dest)); err != nil {
+		return err
+	}
+	if err := os.Rename(action.Path, action.Dest); err != nil {
+		return err
+	}
+	if fileutil.FileExists(lrcPath) {
+		os.Rename(lrcPath, tags.LRCPath(action.Dest))
+	}
+
+	return nil
+}
+
+// archivePath keeps the layout of the output directory inside its Archive
+// folder.
+func archivePath(outputDir, path string) string {
+	rel, err := filepath.Rel(outputDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(path)
+	}
+
+	return filepath.Join(outputDir, "Archive", rel)
+}
+
+// profileSong identifies a song of a profile.
+type profileSong struct {
+	profile, songID string
+}
+
+// songsOfOtherPlaylists returns the songs of the playlists watched by every
+// profile except current, with the ID of a playlist that has each of them.
+func songsOfOtherPlaylists(current *store.WatchedPlaylist) (map[profileSong]string, error) {
+	playlists, err := store.ListWatchedPlaylists()
+	if err != nil {
+		return nil, err
+	}
+
+	songs := make(map[profileSong]string)
+	for _, playlist := range playlists {
+		if playlist.ID == current.ID && playlist.Profile == current.Profile {
+			continue
+		}
+
+		snapshot, err := store.GetPlaylistSnapshot(playlist.Profile, playlist.ID)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot == nil {
+			continue
+		}
+		for _, id := range snapshot.SongIDs {
+			songs[profileSong{playlist.Profile, id}] = playlist.ID
+		}
+	}
+
+	return songs, nil
+}
@@ -0,0 +1,75 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mathismqn/godeez/internal/store"
+)
+
+func TestPlanMirrorOwners(t *testing.T) {
+	dir := t.TempDir()
+	if err := store.OpenDB(dir, ""); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	outputDir := filepath.Join(dir, "Music")
+	path := func(id string) string { return filepath.Join(outputDir, id+".mp3") }
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range []*store.DownloadInfo{
+		{SongID: "1", Profile: "home", Sources: []string{"playlist/5"}},
+		{SongID: "2", Profile: "home", Sources: []string{"playlist/5", "album/10"}},
+		// First downloaded for a playlist that is no longer watched
+		{SongID: "3", Profile: "home", Source: "playlist/9"},
+		// Downloaded by another profile to the same file
+		{SongID: "4", Profile: "home", Sources: []string{"playlist/5"}},
+		{SongID: "4", Profile: "work", Sources: []string{"playlist/7"}},
+	} {
+		info.Path = path(info.SongID)
+		if err := info.Save(); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(info.Path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.AddDownloadSource("home", "3", "playlist/5"); err != nil {
+		t.Fatal(err)
+	}
+
+	playlist := &store.WatchedPlaylist{ID: "5", Profile: "home", Mirror: store.MirrorDelete}
+	other := &store.WatchedPlaylist{ID: "7", Profile: "work"}
+	for _, p := range []*store.WatchedPlaylist{playlist, other} {
+		if _, err := p.Add(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := (&store.PlaylistSnapshot{PlaylistID: "7", Profile: "work", SongIDs: []string{"4"}}).Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	snapshot := &store.PlaylistSnapshot{PlaylistID: "5", Profile: "home", Orphaned: []string{"1", "2", "3", "4"}}
+	actions, err := PlanMirror(playlist, snapshot, outputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{
+		"1": "",
+		"2": "also downloaded for album/10",
+		"3": "",
+		"4": "still in watched playlist 7 of profile work",
+	}
+	if len(actions) != len(want) {
+		t.Fatalf("actions = %v, want %d", actions, len(want))
+	}
+	for _, action := range actions {
+		if action.Skip != want[action.SongID] {
+			t.Errorf("song %s skipped for %q, want %q", action.SongID, action.Skip, want[action.SongID])
+		}
+	}
+}
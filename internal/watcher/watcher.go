@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/mathismqn/godeez/internal/config"
+	"github.com/mathismqn/godeez/internal/deezer"
 	"github.com/mathismqn/godeez/internal/downloader"
 	"github.com/mathismqn/godeez/internal/logger"
 	"github.com/mathismqn/godeez/internal/store"
@@ -35,7 +36,7 @@ func New(appConfig *config.Config) *Watcher {
 	}
 }
 
-func (w *Watcher) Run(ctx context.Context, opts downloader.Options) {
+func (w *Watcher) Run(ctx context.Context) {
 	w.logger.Infof("Starting watcher...")
 
 	for {
@@ -48,9 +49,7 @@ func (w *Watcher) Run(ctx context.Context, opts downloader.Options) {
 				w.logger.Errorf("Failed to list watched playlists: %v\n", err)
 			} else {
 				for _, playlist := range playlists {
-					dl := downloader.New(w.appConfig, "playlist")
-					dl.Logger = w.logger
-					if err := dl.Run(ctx, opts, playlist.ID); err != nil {
+					if err := w.syncPlaylist(ctx, playlist); err != nil {
 						if errors.Is(err, context.Canceled) {
 							return
 						}
@@ -69,3 +68,221 @@ func (w *Watcher) Run(ctx context.Context, opts downloader.Options) {
 		}
 	}
 }
+
+// syncPlaylist downloads the songs added to a playlist since its last
+// snapshot and records the songs that were removed.
+func (w *Watcher) syncPlaylist(ctx context.Context, playlist *store.WatchedPlaylist) error {
+	appConfig, err := w.appConfig.WithProfile(playlist.Profile)
+	if err != nil {
+		return err
+	}
+
+	opts := playlistOptions(playlist, appConfig.Download)
+	dl := downloader.New(appConfig, "playlist")
+	dl.Logger = w.logger
+	// Only the added songs are downloaded, finishSync writes the playlist
+	// file with all of them
+	dl.SkipPlaylistFile = true
+
+	resource, err := dl.Fetch(ctx, opts, playlist.ID)
+	if err != nil {
+		return err
+	}
+
+	snapshot, err := store.GetPlaylistSnapshot(playlist.Profile, playlist.ID)
+	if err != nil {
+		return err
+	}
+	if snapshot == nil {
+		snapshot = &store.PlaylistSnapshot{PlaylistID: playlist.ID, Profile: playlist.Profile}
+	}
+	// Songs removed before this sync are the only ones the mirror policy
+	// acts on, so that a song removed by mistake can be put back in time
+	staged := snapshot.Orphaned
+
+	var checksum string
+	if p, ok := resource.(*deezer.Playlist); ok {
+		checksum = p.Results.Data.Checksum
+	}
+
+	if checksum != "" && checksum == snapshot.Checksum {
+		snapshot.Added = 0
+		snapshot.Removed = nil
+		snapshot.SyncedAt = time.Now()
+
+		return w.finishSync(appConfig, dl, playlist, snapshot, staged, resource, opts)
+	}
+
+	songs := resource.GetSongs()
+	added, removed := diffSongs(snapshot.SongIDs, songs)
+	w.logger.Infof("Playlist %s: %d added, %d removed\n", playlist.ID, len(added), len(removed))
+	for _, id := range removed {
+		w.logger.Infof("Playlist %s: song %s removed\n", playlist.ID, id)
+	}
+
+	if len(added) > 0 {
+		resource.SetSongs(added)
+		err := dl.Download(ctx, opts, resource, playlist.ID)
+		resource.SetSongs(songs)
+		if err != nil {
+			return err
+		}
+	}
+
+	known := make(map[string]bool, len(snapshot.SongIDs))
+	for _, id := range snapshot.SongIDs {
+		known[id] = true
+	}
+
+	complete := true
+	synced := make([]string, 0, len(songs))
+	for _, song := range songs {
+		if !known[song.ID] {
+			if _, err := store.GetDownloadInfo(playlist.Profile, song.ID); err != nil {
+				complete = false
+				continue
+			}
+		}
+		synced = append(synced, song.ID)
+	}
+
+	snapshot.SongIDs = synced
+	snapshot.Added = len(added)
+	snapshot.Removed = removed
+	snapshot.Orphaned = orphanedSongs(snapshot.Orphaned, removed, songs)
+	snapshot.SyncedAt = time.Now()
+	snapshot.Checksum = ""
+	if complete {
+		snapshot.Checksum = checksum
+	}
+
+	return w.finishSync(appConfig, dl, playlist, snapshot, staged, resource, opts)
+}
+
+// finishSync applies the mirror policy, saves the snapshot and regenerates
+// the playlist file so that it reflects the files currently on disk.
+func (w *Watcher) finishSync(appConfig *config.Config, dl *downloader.Client, playlist *store.WatchedPlaylist, snapshot *store.PlaylistSnapshot, staged []string, resource deezer.Resource, opts downloader.Options) error {
+	err := w.mirrorAndSave(appConfig, playlist, snapshot, staged, opts)
+	if errors.Is(err, store.ErrNotWatched) {
+		w.logger.Infof("Playlist %s: removed from the watch list during its sync\n", playlist.ID)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := dl.WritePlaylistFile(opts, resource); err != nil {
+		w.logger.Warnf("Playlist %s: failed to write playlist file: %v\n", playlist.ID, err)
+	}
+
+	return nil
+}
+
+// mirrorAndSave applies the mirror policy of the playlist to its orphaned
+// songs, logging the plan before any file is touched, and saves the
+// snapshot. Only the staged songs, which were already orphaned before this
+// sync, are archived or deleted; the others are left for the next sync.
+func (w *Watcher) mirrorAndSave(appConfig *config.Config, playlist *store.WatchedPlaylist, snapshot *store.PlaylistSnapshot, staged []string, opts downloader.Options) error {
+	outputDir := opts.OutputDir
+	if outputDir == "" {
+		outputDir = appConfig.OutputDir
+	}
+
+	actions, err := PlanMirror(playlist, snapshot, outputDir)
+	if err != nil {
+		w.logger.Errorf("Playlist %s: failed to plan mirror: %v\n", playlist.ID, err)
+	} else if len(actions) > 0 {
+		ready := make(map[string]bool, len(staged))
+		for _, id := range staged {
+			ready[id] = true
+		}
+
+		var due []MirrorAction
+		var pending []string
+		w.logger.Infof("Playlist %s: mirror plan (%s):\n", playlist.ID, playlist.MirrorPolicy())
+		for _, action := range actions {
+			if action.Skip == "" && !ready[action.SongID] {
+				w.logger.Infof("    %s (on the next sync)\n", action)
+				pending = append(pending, action.SongID)
+				continue
+			}
+			w.logger.Infof("    %s\n", action)
+			due = append(due, action)
+		}
+		snapshot.Orphaned = append(pending, w.applyMirror(playlist, due)...)
+	}
+
+	return snapshot.Save()
+}
+
+// orphanedSongs adds the removed songs to the previously orphaned ones and
+// drops those that are back in the playlist.
+func orphanedSongs(orphaned, removed []string, songs []*deezer.Song) []string {
+	current := make(map[string]bool, len(songs))
+	for _, song := range songs {
+		current[song.ID] = true
+	}
+
+	seen := make(map[string]bool)
+	var result []string
+	for _, id := range append(orphaned, removed...) {
+		if current[id] || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+
+	return result
+}
+
+// diffSongs returns the songs that are not in the previous snapshot and the
+// IDs of the snapshot songs that are no longer in the playlist.
+func diffSongs(previous []string, songs []*deezer.Song) ([]*deezer.Song, []string) {
+	current := make(map[string]bool, len(songs))
+	for _, song := range songs {
+		current[song.ID] = true
+	}
+
+	known := make(map[string]bool, len(previous))
+	var removed []string
+	for _, id := range previous {
+		known[id] = true
+		if !current[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	var added []*deezer.Song
+	for _, song := range songs {
+		if !known[song.ID] {
+			added = append(added, song)
+		}
+	}
+
+	return added, removed
+}
+
+// playlistOptions returns the download options stored with a watched
+// playlist, falling back to those a download uses without flags for
+// playlists added before the option existed and for the options not stored.
+func playlistOptions(playlist *store.WatchedPlaylist, d config.Download) downloader.Options {
+	opts := downloader.ConfigOptions(d)
+	if playlist.Quality != "" {
+		opts.Quality = playlist.Quality
+	}
+	if playlist.Timeout > 0 {
+		opts.Timeout = playlist.Timeout
+	}
+	opts.BPM = playlist.BPM
+	opts.Genre = playlist.Genre
+	opts.Strict = playlist.Strict
+	opts.Lyrics = playlist.Lyrics
+	opts.ReplayGain = playlist.ReplayGain
+	opts.OutputDir = playlist.OutputDir
+	opts.Template = playlist.Template
+	// The progress goes to the log of the watcher
+	opts.Output = downloader.OutputText
+
+	return opts
+}